@@ -2,19 +2,31 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/liamwears/reelscore/internal/config"
 	"github.com/liamwears/reelscore/internal/database"
 	"github.com/liamwears/reelscore/internal/handlers"
+	"github.com/liamwears/reelscore/internal/logging"
+	"github.com/liamwears/reelscore/internal/metrics"
 	"github.com/liamwears/reelscore/internal/middleware"
+	"github.com/liamwears/reelscore/internal/models"
 	"github.com/liamwears/reelscore/internal/services"
+	"github.com/liamwears/reelscore/internal/tracing"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func main() {
@@ -24,22 +36,47 @@ func main() {
 		return
 	}
 
+	// Check for export command
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		runExport(os.Args[2:])
+		return
+	}
+
+	// Check for import command
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		runImport(os.Args[2:])
+		return
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Initialize logger
-	logger := log.New(os.Stdout, "[reelscore] ", log.LstdFlags|log.Lshortfile)
-	logger.Printf("Starting ReelScore server in %s mode", cfg.Server.Env)
+	// Initialize logger, also used for anything written through the log package
+	logger := logging.New(os.Stdout, cfg.Server.LogLevel, cfg.IsProduction())
+	slog.SetDefault(logger)
+	logger.Info("Starting ReelScore server", "env", cfg.Server.Env)
+
+	// Initialize tracing before any connection is made so their spans are recorded
+	tracerProvider, err := newTracerProvider(cfg.Tracing)
+	if err != nil {
+		logger.Error("Failed to create trace exporter", "error", err)
+		os.Exit(1)
+	}
+	if tracerProvider != nil {
+		tracing.SetProvider(tracerProvider)
+		logger.Info("Tracing enabled", "exporter", cfg.Tracing.Exporter, "sample_ratio", cfg.Tracing.SampleRatio)
+	}
 
 	// Initialize database connection
 	db, err := database.New(database.Config{
 		URL: cfg.Database.URL,
 	})
 	if err != nil {
-		logger.Fatalf("Failed to connect to database: %v", err)
+		logger.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
 	}
 	defer db.Close()
 
@@ -51,43 +88,88 @@ func main() {
 		TLS:      cfg.Redis.TLS,
 	})
 	if err != nil {
-		logger.Fatalf("Failed to connect to Redis: %v", err)
+		logger.Error("Failed to connect to Redis", "error", err)
+		os.Exit(1)
 	}
 	defer redisClient.Close()
 
 	// Initialize session store
 	sessionStore := database.NewSessionStore(redisClient, 7*24*time.Hour)
+	oauthStateStore := database.NewOAuthStateStore(redisClient, 10*time.Minute)
 
 	// Initialize services
 	userService := services.NewUserService(db.Pool)
+	apiTokenService := services.NewAPITokenService(db.Pool)
 	movieService := services.NewMovieService(db.Pool)
 	serieService := services.NewSerieService(db.Pool)
+
+	// Cache TMDB responses in Redis unless disabled
+	var tmdbCache *services.TMDBCache
+	if cfg.TMDB.CacheEnabled {
+		tmdbCache = services.NewTMDBCache(redisClient.Client, services.TMDBCacheConfig{})
+	}
 	tmdbService := services.NewTMDBService(services.TMDBConfig{
 		APIKey:       cfg.TMDB.APIKey,
 		BaseURL:      "https://api.themoviedb.org/3",
 		ImageBaseURL: "https://image.tmdb.org/t/p/w500",
+		Cache:        tmdbCache,
 	})
+	progressService := services.NewProgressService(db.Pool, serieService, tmdbService)
+	viewingService := services.NewViewingService(db.Pool)
+	importService := services.NewImportService(tmdbService, movieService, viewingService)
+	exportService := services.NewExportService(movieService, serieService)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(sessionStore, userService, "session", cfg.IsProduction())
-
-	// Initialize rate limiter (100 req/min in production, unlimited in local/dev)
-	maxRequests := 1000 // High limit for local/dev
-	if cfg.IsProduction() {
-		maxRequests = 100
+	authMiddleware := middleware.NewAuthMiddleware(sessionStore, userService, apiTokenService, "session", cfg.IsProduction())
+	csrf := middleware.NewCSRF(cfg.Session.SecretKey, "session")
+	clientIPResolver, err := middleware.NewClientIPResolver(cfg.Server.TrustedProxies, cfg.Server.ForwardedHeader)
+	if err != nil {
+		logger.Error("Failed to configure trusted proxies", "error", err)
+		os.Exit(1)
 	}
-	rateLimiter := middleware.NewRateLimiter(redisClient.Client, maxRequests, time.Minute, cfg.IsProduction())
+
+	// Initialize rate limiter with a budget per kind of route (off in local/dev unless enabled)
+	rateLimiter := middleware.NewRateLimiter(redisClient.Client, middleware.RateLimiterConfig{
+		Enabled:     cfg.RateLimit.Enabled,
+		FailureMode: middleware.RateLimitFailureMode(cfg.RateLimit.FailureMode),
+	})
+	readLimit := middleware.RateLimitPolicy{Name: "read", MaxRequests: cfg.RateLimit.Read, Window: time.Minute}
+	writeLimit := middleware.RateLimitPolicy{Name: "write", MaxRequests: cfg.RateLimit.Write, Window: time.Minute}
+	tmdbLimit := middleware.RateLimitPolicy{Name: "tmdb", MaxRequests: cfg.RateLimit.TMDB, Window: time.Minute}
+	accountLimit := middleware.RateLimitPolicy{Name: "account", MaxRequests: cfg.RateLimit.Account, Window: time.Minute}
 
 	// Initialize renderer
 	renderer, err := handlers.NewRenderer(logger)
 	if err != nil {
-		logger.Fatalf("Failed to initialize renderer: %v", err)
+		logger.Error("Failed to initialize renderer", "error", err)
+		os.Exit(1)
+	}
+
+	// Register configured OpenID Connect providers
+	var oidcProviders []*services.OIDCProvider
+	for _, provider := range cfg.OAuth.OIDC {
+		oidcProviders = append(oidcProviders, services.NewOIDCProvider(services.OIDCProviderConfig{
+			Name:         provider.Name,
+			DisplayName:  provider.DisplayName,
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  fmt.Sprintf("%s/auth/oidc/%s/callback", cfg.OAuth.CallbackHost, provider.Name),
+			Scopes:       provider.Scopes,
+			Claims: services.OIDCClaims{
+				Subject: provider.SubjectClaim,
+				Email:   provider.EmailClaim,
+				Name:    provider.NameClaim,
+			},
+		}))
+		logger.Info("OIDC provider registered", "provider", provider.Name, "issuer", provider.Issuer)
 	}
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(
 		userService,
 		sessionStore,
+		oauthStateStore,
 		authMiddleware,
 		renderer,
 		handlers.AuthConfig{
@@ -96,23 +178,33 @@ func main() {
 			GitHubClientID:     cfg.OAuth.GitHubClientID,
 			GitHubClientSecret: cfg.OAuth.GitHubClientSecret,
 			CallbackHost:       cfg.OAuth.CallbackHost,
+			OIDCProviders:      oidcProviders,
 		},
 		logger,
 	)
+	accountHandler := handlers.NewAccountHandler(userService, apiTokenService, exportService, sessionStore, authMiddleware, oidcProviders, renderer, logger)
+	apiTokenHandler := handlers.NewAPITokenHandler(apiTokenService, logger)
 	movieHandler := handlers.NewMovieHandler(movieService, logger)
 	serieHandler := handlers.NewSerieHandler(serieService, logger)
+	progressHandler := handlers.NewProgressHandler(progressService, logger)
+	viewingHandler := handlers.NewViewingHandler(viewingService, logger)
+	importHandler := handlers.NewImportHandler(importService, logger)
+	exportHandler := handlers.NewExportHandler(exportService, logger)
 	tmdbHandler := handlers.NewTMDBHandler(tmdbService, logger)
-	pageHandler := handlers.NewPageHandler(tmdbService, movieService, serieService, renderer, logger)
+	pageHandler := handlers.NewPageHandler(tmdbService, movieService, serieService, viewingService, renderer, logger)
 
 	// Set up HTTP router with logging
 	mux := http.NewServeMux()
 
 	// Auth routes (public)
 	mux.HandleFunc("/login", authHandler.Login)
-	mux.HandleFunc("/auth/google/login", authHandler.GoogleLogin)
-	mux.HandleFunc("/auth/google/callback", authHandler.GoogleCallback)
-	mux.HandleFunc("/auth/github/login", authHandler.GitHubLogin)
-	mux.HandleFunc("/auth/github/callback", authHandler.GitHubCallback)
+	// Provider routes see the current user, if any, so they can link providers to it
+	mux.Handle("/auth/google/login", authMiddleware.OptionalAuth(http.HandlerFunc(authHandler.GoogleLogin)))
+	mux.Handle("/auth/google/callback", authMiddleware.OptionalAuth(http.HandlerFunc(authHandler.GoogleCallback)))
+	mux.Handle("/auth/github/login", authMiddleware.OptionalAuth(http.HandlerFunc(authHandler.GitHubLogin)))
+	mux.Handle("/auth/github/callback", authMiddleware.OptionalAuth(http.HandlerFunc(authHandler.GitHubCallback)))
+	mux.Handle("GET /auth/oidc/{name}/login", authMiddleware.OptionalAuth(http.HandlerFunc(authHandler.OIDCLogin)))
+	mux.Handle("GET /auth/oidc/{name}/callback", authMiddleware.OptionalAuth(http.HandlerFunc(authHandler.OIDCCallback)))
 	mux.HandleFunc("/auth/logout", authHandler.Logout)
 
 	// Page routes (protected)
@@ -121,36 +213,98 @@ func main() {
 	mux.Handle("/search", authMiddleware.RequireAuth(http.HandlerFunc(pageHandler.Search)))
 	mux.Handle("/library/movies/{type}", authMiddleware.RequireAuth(http.HandlerFunc(pageHandler.LibraryMovies)))
 	mux.Handle("/library/series/{type}", authMiddleware.RequireAuth(http.HandlerFunc(pageHandler.LibrarySeries)))
+	mux.Handle("/diary", authMiddleware.RequireAuth(http.HandlerFunc(pageHandler.Diary)))
+	mux.Handle("GET /account", authMiddleware.RequireAuth(http.HandlerFunc(accountHandler.Settings)))
+	mux.Handle("POST /account/tokens", authMiddleware.RequireAuth(http.HandlerFunc(accountHandler.CreateToken)))
+	mux.Handle("GET /account/devices", authMiddleware.RequireAuth(http.HandlerFunc(accountHandler.Devices)))
+	mux.Handle("GET /account/data", authMiddleware.RequireAuth(http.HandlerFunc(accountHandler.DownloadData)))
+	mux.Handle("POST /account/delete", authMiddleware.RequireAuth(http.HandlerFunc(accountHandler.ScheduleDeletion)))
+	mux.Handle("POST /account/delete/cancel", authMiddleware.RequireAuth(http.HandlerFunc(accountHandler.CancelDeletion)))
 
 	// Movie API routes (protected with auth and rate limiting)
-	mux.Handle("GET /api/movies", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(movieHandler.List))))
-	mux.Handle("POST /api/movies", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(movieHandler.Create))))
-	mux.Handle("GET /api/movies/{id}", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(movieHandler.Get))))
-	mux.Handle("PATCH /api/movies/{id}", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(movieHandler.Update))))
-	mux.Handle("DELETE /api/movies/{id}", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(movieHandler.Delete))))
+	mux.Handle("GET /api/movies", authMiddleware.RequireAuthAPI(rateLimiter.Limit(readLimit, http.HandlerFunc(movieHandler.List))))
+	mux.Handle("POST /api/movies", authMiddleware.RequireAuthAPI(rateLimiter.Limit(writeLimit, http.HandlerFunc(movieHandler.Create))))
+	mux.Handle("GET /api/movies/{id}", authMiddleware.RequireAuthAPI(rateLimiter.Limit(readLimit, http.HandlerFunc(movieHandler.Get))))
+	mux.Handle("PATCH /api/movies/{id}", authMiddleware.RequireAuthAPI(rateLimiter.Limit(writeLimit, http.HandlerFunc(movieHandler.Update))))
+	mux.Handle("DELETE /api/movies/{id}", authMiddleware.RequireAuthAPI(rateLimiter.Limit(writeLimit, http.HandlerFunc(movieHandler.Delete))))
+
+	// Viewing API routes (protected with auth and rate limiting)
+	mux.Handle("GET /api/movies/{id}/viewings", authMiddleware.RequireAuthAPI(rateLimiter.Limit(readLimit, http.HandlerFunc(viewingHandler.List))))
+	mux.Handle("POST /api/movies/{id}/viewings", authMiddleware.RequireAuthAPI(rateLimiter.Limit(writeLimit, http.HandlerFunc(viewingHandler.Create))))
+	mux.Handle("DELETE /api/movies/{id}/viewings/{viewingId}", authMiddleware.RequireAuthAPI(rateLimiter.Limit(writeLimit, http.HandlerFunc(viewingHandler.Delete))))
 
 	// Serie API routes (protected with auth and rate limiting)
-	mux.Handle("GET /api/series", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(serieHandler.List))))
-	mux.Handle("POST /api/series", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(serieHandler.Create))))
-	mux.Handle("GET /api/series/{id}", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(serieHandler.Get))))
-	mux.Handle("PATCH /api/series/{id}", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(serieHandler.Update))))
-	mux.Handle("DELETE /api/series/{id}", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(serieHandler.Delete))))
+	mux.Handle("GET /api/series", authMiddleware.RequireAuthAPI(rateLimiter.Limit(readLimit, http.HandlerFunc(serieHandler.List))))
+	mux.Handle("POST /api/series", authMiddleware.RequireAuthAPI(rateLimiter.Limit(writeLimit, http.HandlerFunc(serieHandler.Create))))
+	mux.Handle("GET /api/series/{id}", authMiddleware.RequireAuthAPI(rateLimiter.Limit(readLimit, http.HandlerFunc(serieHandler.Get))))
+	mux.Handle("PATCH /api/series/{id}", authMiddleware.RequireAuthAPI(rateLimiter.Limit(writeLimit, http.HandlerFunc(serieHandler.Update))))
+	mux.Handle("DELETE /api/series/{id}", authMiddleware.RequireAuthAPI(rateLimiter.Limit(writeLimit, http.HandlerFunc(serieHandler.Delete))))
+
+	// Import/export API routes (protected with auth and rate limiting)
+	mux.Handle("POST /api/import/letterboxd", authMiddleware.RequireAuthAPI(rateLimiter.Limit(writeLimit, http.HandlerFunc(importHandler.Letterboxd))))
+	mux.Handle("GET /api/export", authMiddleware.RequireAuthAPI(rateLimiter.Limit(readLimit, http.HandlerFunc(exportHandler.Export))))
+
+	// Account API routes (browser session only)
+	mux.Handle("DELETE /api/account/identities/{id}", authMiddleware.RequireSessionAPI(rateLimiter.Limit(accountLimit, http.HandlerFunc(accountHandler.UnlinkIdentity))))
+	mux.Handle("DELETE /api/sessions", authMiddleware.RequireSessionAPI(rateLimiter.Limit(accountLimit, http.HandlerFunc(accountHandler.LogoutEverywhere))))
+	mux.Handle("DELETE /api/sessions/{handle}", authMiddleware.RequireSessionAPI(rateLimiter.Limit(accountLimit, http.HandlerFunc(accountHandler.RevokeSession))))
+
+	// Personal access token API routes (browser session only, tokens can't mint tokens)
+	mux.Handle("GET /api/tokens", authMiddleware.RequireSessionAPI(rateLimiter.Limit(accountLimit, http.HandlerFunc(apiTokenHandler.List))))
+	mux.Handle("POST /api/tokens", authMiddleware.RequireSessionAPI(rateLimiter.Limit(accountLimit, http.HandlerFunc(apiTokenHandler.Create))))
+	mux.Handle("DELETE /api/tokens/{id}", authMiddleware.RequireSessionAPI(rateLimiter.Limit(accountLimit, http.HandlerFunc(apiTokenHandler.Revoke))))
+
+	// Episode progress API routes (protected with auth and rate limiting)
+	mux.Handle("GET /api/series/{id}/progress", authMiddleware.RequireAuthAPI(rateLimiter.Limit(readLimit, http.HandlerFunc(progressHandler.Get))))
+	mux.Handle("POST /api/series/{id}/progress", authMiddleware.RequireAuthAPI(rateLimiter.Limit(writeLimit, http.HandlerFunc(progressHandler.Mark))))
+	mux.Handle("DELETE /api/series/{id}/progress", authMiddleware.RequireAuthAPI(rateLimiter.Limit(writeLimit, http.HandlerFunc(progressHandler.Unmark))))
 
 	// TMDB API routes (protected with auth and rate limiting)
-	mux.Handle("GET /api/tmdb/movie/{id}", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(tmdbHandler.GetMovie))))
-	mux.Handle("GET /api/tmdb/tv/{id}", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(tmdbHandler.GetTV))))
-	mux.Handle("GET /api/tmdb/search/multi", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(tmdbHandler.SearchMulti))))
-	mux.Handle("GET /api/tmdb/search/movie", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(tmdbHandler.SearchMovies))))
-	mux.Handle("GET /api/tmdb/search/tv", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(tmdbHandler.SearchTV))))
-	mux.Handle("GET /api/tmdb/discover/movie", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(tmdbHandler.DiscoverMovies))))
-	mux.Handle("GET /api/tmdb/discover/tv", rateLimiter.Limit(authMiddleware.RequireAuthAPI(http.HandlerFunc(tmdbHandler.DiscoverTV))))
+	mux.Handle("GET /api/tmdb/movie/{id}", authMiddleware.RequireAuthAPI(rateLimiter.Limit(tmdbLimit, http.HandlerFunc(tmdbHandler.GetMovie))))
+	mux.Handle("GET /api/tmdb/movie/{id}/details", authMiddleware.RequireAuthAPI(rateLimiter.Limit(tmdbLimit, http.HandlerFunc(tmdbHandler.GetMovieDetails))))
+	mux.Handle("GET /api/tmdb/tv/{id}", authMiddleware.RequireAuthAPI(rateLimiter.Limit(tmdbLimit, http.HandlerFunc(tmdbHandler.GetTV))))
+	mux.Handle("GET /api/tmdb/tv/{id}/details", authMiddleware.RequireAuthAPI(rateLimiter.Limit(tmdbLimit, http.HandlerFunc(tmdbHandler.GetTVDetails))))
+	mux.Handle("GET /api/tmdb/tv/{id}/season/{n}", authMiddleware.RequireAuthAPI(rateLimiter.Limit(tmdbLimit, http.HandlerFunc(tmdbHandler.GetSeason))))
+	mux.Handle("GET /api/tmdb/tv/{id}/season/{n}/episode/{e}", authMiddleware.RequireAuthAPI(rateLimiter.Limit(tmdbLimit, http.HandlerFunc(tmdbHandler.GetEpisode))))
+	mux.Handle("GET /api/tmdb/search/multi", authMiddleware.RequireAuthAPI(rateLimiter.Limit(tmdbLimit, http.HandlerFunc(tmdbHandler.SearchMulti))))
+	mux.Handle("GET /api/tmdb/search/movie", authMiddleware.RequireAuthAPI(rateLimiter.Limit(tmdbLimit, http.HandlerFunc(tmdbHandler.SearchMovies))))
+	mux.Handle("GET /api/tmdb/search/tv", authMiddleware.RequireAuthAPI(rateLimiter.Limit(tmdbLimit, http.HandlerFunc(tmdbHandler.SearchTV))))
+	mux.Handle("GET /api/tmdb/discover/movie", authMiddleware.RequireAuthAPI(rateLimiter.Limit(tmdbLimit, http.HandlerFunc(tmdbHandler.DiscoverMovies))))
+	mux.Handle("GET /api/tmdb/discover/tv", authMiddleware.RequireAuthAPI(rateLimiter.Limit(tmdbLimit, http.HandlerFunc(tmdbHandler.DiscoverTV))))
 
 	// Serve static files
 	fs := http.FileServer(http.Dir("internal/static"))
 	mux.Handle("/static/", http.StripPrefix("/static/", fs))
 
-	// Wrap with logging middleware
-	handler := middleware.Logger(logger)(mux)
+	// Security headers, with the HTMX friendly default policy unless overridden
+	contentSecurityPolicy := cfg.Security.ContentSecurityPolicy
+	switch contentSecurityPolicy {
+	case "":
+		contentSecurityPolicy = middleware.DefaultContentSecurityPolicy
+	case "off":
+		contentSecurityPolicy = ""
+	}
+	securityHeaders := middleware.SecurityHeaders(middleware.SecurityHeadersConfig{
+		ContentSecurityPolicy: contentSecurityPolicy,
+		HSTSMaxAge:            time.Duration(cfg.Security.HSTSMaxAge) * time.Second,
+	})
+
+	// Wrap the router, outermost first. The client IP is resolved before anything
+	// uses it, and panics are recovered inside logging so they are logged as 500s.
+	handler := middleware.Chain(
+		clientIPResolver.Resolve,
+		middleware.Trace,
+		middleware.Logger(logger),
+		middleware.Recover(logger),
+		securityHeaders,
+		middleware.Compress,
+		csrf.Protect,
+		middleware.RecordRoute,
+	)(mux)
+
+	// Prometheus metrics endpoint (bearer token protected when METRICS_TOKEN is set)
+	registerMetrics(db, sessionStore, tmdbService)
+	mux.Handle("GET /metrics", metrics.Handler(cfg.Metrics.Token))
 
 	// Health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -192,36 +346,179 @@ func main() {
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
 	}
 
 	// Start server in a goroutine
 	go func() {
-		logger.Printf("Server listening on %s", addr)
+		logger.Info("Server listening", "addr", addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatalf("Server failed to start: %v", err)
+			logger.Error("Server failed to start", "error", err)
+			os.Exit(1)
 		}
 	}()
 
+	// Delete accounts whose deletion grace period has ended
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	go purgeDeletedAccounts(purgeCtx, userService, sessionStore, logger)
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	logger.Println("Shutting down server...")
+	logger.Info("Shutting down server")
+	stopPurge()
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		logger.Fatalf("Server forced to shutdown: %v", err)
+		logger.Error("Server forced to shutdown", "error", err)
+		os.Exit(1)
+	}
+
+	// Export the spans still queued
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			logger.Error("Failed to flush traces", "error", err)
+		}
 	}
 
 	// Close connections
 	db.Close()
 	redisClient.Close()
 
-	logger.Println("Server exited")
+	logger.Info("Server exited")
+}
+
+// purgeDeletedAccounts deletes accounts past their deletion grace period every hour,
+// then signs them out everywhere. An account whose owner cancelled the deletion after
+// it was listed is left alone.
+func purgeDeletedAccounts(ctx context.Context, userService *services.UserService, sessionStore *database.SessionStore, logger *slog.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		ids, err := userService.DueForDeletion(ctx)
+		if err != nil {
+			logger.Error("Failed to find accounts due for deletion", "error", err)
+		}
+		for _, id := range ids {
+			deleted, err := userService.DeleteIfDue(ctx, id)
+			if err != nil {
+				logger.Error("Failed to delete user", "user_id", id, "error", err)
+				continue
+			}
+			if !deleted {
+				continue
+			}
+			logger.Info("Deleted account", "user_id", id)
+
+			// Sessions of a deleted user no longer authenticate, this just frees them
+			if err := sessionStore.DeleteUser(ctx, id); err != nil {
+				logger.Error("Failed to delete sessions of user", "user_id", id, "error", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// newTracerProvider creates the provider for the configured exporter, or nil when
+// tracing is disabled
+func newTracerProvider(cfg config.TracingConfig) (*sdktrace.TracerProvider, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "otlp":
+		exporter, err = otlptracehttp.New(context.Background(),
+			otlptracehttp.WithEndpointURL(strings.TrimSuffix(cfg.Endpoint, "/")+"/v1/traces"),
+			otlptracehttp.WithHeaders(cfg.Headers),
+		)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tracing.NewProvider(exporter, tracing.ProviderConfig{
+		ServiceName: cfg.ServiceName,
+		SampleRatio: cfg.SampleRatio,
+	}), nil
+}
+
+// registerMetrics exposes connection pool, session and TMDB cache metrics read at
+// scrape time
+func registerMetrics(db *database.DB, sessionStore *database.SessionStore, tmdbService *services.TMDBService) {
+	metrics.NewGaugeFunc("reelscore_db_pool_acquired_connections",
+		"Postgres connections currently in use.", func() float64 {
+			return float64(db.Pool.Stat().AcquiredConns())
+		})
+	metrics.NewGaugeFunc("reelscore_db_pool_idle_connections",
+		"Postgres connections currently idle.", func() float64 {
+			return float64(db.Pool.Stat().IdleConns())
+		})
+	metrics.NewGaugeFunc("reelscore_db_pool_total_connections",
+		"Postgres connections currently open.", func() float64 {
+			return float64(db.Pool.Stat().TotalConns())
+		})
+	metrics.NewCounterFunc("reelscore_db_pool_acquires_total",
+		"Postgres connections acquired from the pool.", func() float64 {
+			return float64(db.Pool.Stat().AcquireCount())
+		})
+	metrics.NewCounterFunc("reelscore_db_pool_empty_acquires_total",
+		"Acquires that had to wait for a connection.", func() float64 {
+			return float64(db.Pool.Stat().EmptyAcquireCount())
+		})
+	metrics.NewCounterFunc("reelscore_db_pool_acquire_wait_seconds_total",
+		"Time spent waiting for Postgres connections, in seconds.", func() float64 {
+			return db.Pool.Stat().AcquireDuration().Seconds()
+		})
+
+	// The TMDB cache counters are only exposed while the cache is enabled
+	if tmdbService.CacheStats() != nil {
+		metrics.NewCounterFunc("reelscore_tmdb_cache_hits_total",
+			"TMDB responses served fresh from the cache.", func() float64 {
+				return float64(tmdbService.CacheStats().Hits)
+			})
+		metrics.NewCounterFunc("reelscore_tmdb_cache_stale_total",
+			"TMDB responses served stale from the cache while being refreshed.", func() float64 {
+				return float64(tmdbService.CacheStats().Stale)
+			})
+		metrics.NewCounterFunc("reelscore_tmdb_cache_misses_total",
+			"TMDB responses not in the cache and fetched upstream.", func() float64 {
+				return float64(tmdbService.CacheStats().Misses)
+			})
+	}
+
+	// Counting sessions scans Redis, so the count is reused for half a minute
+	var mu sync.Mutex
+	var sessions float64
+	var countedAt time.Time
+	metrics.NewGaugeFunc("reelscore_active_sessions",
+		"Browser sessions that haven't expired.", func() float64 {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if time.Since(countedAt) > 30*time.Second {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				defer cancel()
+				if count, err := sessionStore.Count(ctx); err == nil {
+					sessions = float64(count)
+					countedAt = time.Now()
+				}
+			}
+			return sessions
+		})
 }
 
 // runMigrations runs database migrations
@@ -248,3 +545,124 @@ func runMigrations() {
 
 	log.Println("Migrations completed successfully")
 }
+
+// runImport runs the import subcommand, e.g. `reelscore import letterboxd -user me@example.com export.zip`
+func runImport(args []string) {
+	if len(args) < 1 || args[0] != "letterboxd" {
+		log.Fatalf("Usage: reelscore import letterboxd -user <email> <file>")
+	}
+
+	fs := flag.NewFlagSet("import letterboxd", flag.ExitOnError)
+	email := fs.String("user", "", "email of the user to import into")
+	fs.Parse(args[1:])
+
+	if *email == "" || fs.NArg() != 1 {
+		log.Fatalf("Usage: reelscore import letterboxd -user <email> <file>")
+	}
+	file := fs.Arg(0)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	db, err := database.New(database.Config{
+		URL: cfg.Database.URL,
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", file, err)
+	}
+
+	ctx := context.Background()
+
+	userService := services.NewUserService(db.Pool)
+	user, err := userService.FindByEmail(ctx, *email)
+	if err != nil {
+		log.Fatalf("Failed to find user %s: %v", *email, err)
+	}
+
+	tmdbService := services.NewTMDBService(services.TMDBConfig{
+		APIKey:       cfg.TMDB.APIKey,
+		BaseURL:      "https://api.themoviedb.org/3",
+		ImageBaseURL: "https://image.tmdb.org/t/p/w500",
+	})
+	importService := services.NewImportService(
+		tmdbService,
+		services.NewMovieService(db.Pool),
+		services.NewViewingService(db.Pool),
+	)
+
+	report, err := importService.ImportLetterboxd(ctx, user.ID, filepath.Base(file), data)
+	if err != nil {
+		log.Fatalf("Failed to import Letterboxd export: %v", err)
+	}
+
+	for _, row := range report.Rows {
+		switch row.Status {
+		case models.ImportStatusAmbiguous:
+			fmt.Printf("ambiguous  %s (%s): %d candidates\n", row.Name, row.Year, len(row.Candidates))
+		case models.ImportStatusUnmatched:
+			fmt.Printf("unmatched  %s (%s)\n", row.Name, row.Year)
+		case models.ImportStatusFailed:
+			fmt.Printf("failed     %s (%s): %s\n", row.Name, row.Year, row.Error)
+		}
+	}
+
+	fmt.Printf("Imported %d, already in library %d, ambiguous %d, unmatched %d, failed %d\n",
+		report.Matched, report.Existing, report.Ambiguous, report.Unmatched, report.Failed)
+}
+
+// runExport runs the export subcommand, e.g. `reelscore export -user me@example.com -format csv -o backup.csv`
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	email := fs.String("user", "", "email of the user to export")
+	format := fs.String("format", "json", "export format: csv, json or letterboxd")
+	output := fs.String("o", "", "output file (default stdout)")
+	fs.Parse(args)
+
+	exportFormat := services.ExportFormat(*format)
+	if *email == "" || !exportFormat.IsValid() {
+		log.Fatalf("Usage: reelscore export -user <email> [-format csv|json|letterboxd] [-o file]")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	db, err := database.New(database.Config{
+		URL: cfg.Database.URL,
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	userService := services.NewUserService(db.Pool)
+	user, err := userService.FindByEmail(ctx, *email)
+	if err != nil {
+		log.Fatalf("Failed to find user %s: %v", *email, err)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *output, err)
+		}
+		defer out.Close()
+	}
+
+	exportService := services.NewExportService(services.NewMovieService(db.Pool), services.NewSerieService(db.Pool))
+	if err := exportService.Export(ctx, user.ID, exportFormat, out); err != nil {
+		log.Fatalf("Failed to export library: %v", err)
+	}
+}
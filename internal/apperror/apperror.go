@@ -0,0 +1,205 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Code identifies the kind of an error in API responses
+type Code string
+
+const (
+	CodeBadRequest          Code = "bad_request"
+	CodeValidation          Code = "validation_failed"
+	CodeUnauthorized        Code = "unauthorized"
+	CodeForbidden           Code = "forbidden"
+	CodeNotFound            Code = "not_found"
+	CodeConflict            Code = "conflict"
+	CodeRateLimited         Code = "rate_limited"
+	CodeInternal            Code = "internal"
+	CodeUnavailable         Code = "unavailable"
+	CodeUpstreamUnavailable Code = "upstream_unavailable"
+)
+
+// Status returns the HTTP status code for the kind of error
+func (c Code) Status() int {
+	switch c {
+	case CodeBadRequest, CodeValidation:
+		return http.StatusBadRequest
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeForbidden:
+		return http.StatusForbidden
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
+	case CodeUnavailable, CodeUpstreamUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error is an error with a code and a message safe to show to API clients
+type Error struct {
+	Code Code
+	// Message describes the error to the client
+	Message string
+	// Fields maps invalid input fields to what is wrong with them
+	Fields map[string]string
+	// Err is the underlying cause, kept for logs and errors.As but never sent
+	Err error
+}
+
+// Kinds of error to test for with errors.Is. They match any error of their code, so
+// errors.Is(err, ErrNotFound) holds for every not found error, whatever its message.
+var (
+	ErrNotFound            = &Error{Code: CodeNotFound}
+	ErrConflict            = &Error{Code: CodeConflict}
+	ErrValidation          = &Error{Code: CodeValidation}
+	ErrUpstreamUnavailable = &Error{Code: CodeUpstreamUnavailable}
+)
+
+// Error returns the message followed by the cause, if any
+func (e *Error) Error() string {
+	message := e.Message
+	if message == "" {
+		message = string(e.Code)
+	}
+	if e.Err != nil {
+		return message + ": " + e.Err.Error()
+	}
+	return message
+}
+
+// Unwrap returns the underlying cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the kind of error e is, like ErrNotFound. Errors with
+// a message only match themselves.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Message == "" && t.Code == e.Code
+}
+
+// Status returns the HTTP status code for the error
+func (e *Error) Status() int {
+	return e.Code.Status()
+}
+
+// New creates an error with the given code and message
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// BadRequest creates an error for malformed requests, like an invalid ID or body
+func BadRequest(message string) *Error {
+	return New(CodeBadRequest, message)
+}
+
+// Validation creates an error for well-formed input that is invalid. Fields may be nil.
+func Validation(message string, fields map[string]string) *Error {
+	return &Error{Code: CodeValidation, Message: message, Fields: fields}
+}
+
+// Unauthorized creates an error for requests without valid credentials
+func Unauthorized(message string) *Error {
+	return New(CodeUnauthorized, message)
+}
+
+// Forbidden creates an error for requests the credentials don't allow
+func Forbidden(message string) *Error {
+	return New(CodeForbidden, message)
+}
+
+// NotFound creates an error for missing resources
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+// Conflict creates an error for requests clashing with existing data, like duplicates
+func Conflict(message string) *Error {
+	return New(CodeConflict, message)
+}
+
+// RateLimited creates an error for requests over their rate limit
+func RateLimited(message string) *Error {
+	return New(CodeRateLimited, message)
+}
+
+// Internal creates an error for unexpected failures
+func Internal(message string) *Error {
+	return New(CodeInternal, message)
+}
+
+// Unavailable creates an error for when the server can't handle requests right now
+func Unavailable(message string) *Error {
+	return New(CodeUnavailable, message)
+}
+
+// UpstreamUnavailable creates an error for when a service we depend on, like TMDB,
+// is failing. Cause may be nil.
+func UpstreamUnavailable(message string, cause error) *Error {
+	return &Error{Code: CodeUpstreamUnavailable, Message: message, Err: cause}
+}
+
+// Postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgNotNullViolation    = "23502"
+	pgCheckViolation      = "23514"
+	pgStringTooLong       = "22001"
+	pgInvalidText         = "22P02"
+)
+
+// FromPostgres translates Postgres errors into application errors wrapping them: no
+// rows becomes not found, unique and foreign key violations become conflicts and
+// invalid values become validation errors. Other errors are returned unchanged.
+func FromPostgres(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Error{Code: CodeNotFound, Message: "Not found", Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	var fields map[string]string
+	if pgErr.ColumnName != "" {
+		fields = map[string]string{pgErr.ColumnName: "Invalid value"}
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return &Error{Code: CodeConflict, Message: "Already exists", Err: err}
+	case pgForeignKeyViolation:
+		return &Error{Code: CodeConflict, Message: "Refers to a record that doesn't exist or is still in use", Err: err}
+	case pgNotNullViolation, pgCheckViolation, pgStringTooLong, pgInvalidText:
+		return &Error{Code: CodeValidation, Message: "Invalid value", Fields: fields, Err: err}
+	default:
+		return err
+	}
+}
+
+// From returns err as an application error: the *Error it wraps, a translated
+// Postgres error, or an internal error hiding anything else
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) || errors.As(FromPostgres(err), &appErr) {
+		return appErr
+	}
+	return &Error{Code: CodeInternal, Message: "Internal server error", Err: err}
+}
@@ -0,0 +1,43 @@
+package apperror
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/liamwears/reelscore/internal/logging"
+)
+
+// Response is the JSON body of every API error response
+type Response struct {
+	Error ResponseError `json:"error"`
+}
+
+// ResponseError describes the error, with the request ID to quote in bug reports
+type ResponseError struct {
+	Code      Code              `json:"code"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// Write writes err as a JSON error response with the matching status code. Errors
+// that aren't application errors are sent as internal errors without their details.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := From(err)
+
+	message := appErr.Message
+	if message == "" {
+		message = http.StatusText(appErr.Status())
+	}
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(appErr.Status())
+	json.NewEncoder(w).Encode(Response{Error: ResponseError{
+		Code:      appErr.Code,
+		Message:   message,
+		Fields:    appErr.Fields,
+		RequestID: logging.RequestID(r.Context()),
+	}})
+}
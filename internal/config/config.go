@@ -2,24 +2,39 @@ package config
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	OAuth    OAuthConfig
-	TMDB     TMDBConfig
-	Session  SessionConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	OAuth     OAuthConfig
+	TMDB      TMDBConfig
+	Session   SessionConfig
+	RateLimit RateLimitConfig
+	Metrics   MetricsConfig
+	Tracing   TracingConfig
+	Security  SecurityConfig
 }
 
 type ServerConfig struct {
 	Env  string
 	Port string
 	Host string
+	// LogLevel is the minimum level logged: debug, info, warn or error
+	LogLevel string
+	// TrustedProxies are the CIDR ranges of proxies whose forwarding headers are believed
+	TrustedProxies []string
+	// ForwardedHeader is the header the trusted proxies set: X-Forwarded-For or Forwarded
+	ForwardedHeader string
 }
 
 type DatabaseConfig struct {
@@ -39,18 +54,73 @@ type OAuthConfig struct {
 	GitHubClientID     string
 	GitHubClientSecret string
 	CallbackHost       string
+	OIDC               []OIDCProviderConfig
+}
+
+// OIDCProviderConfig configures one OpenID Connect provider, read from
+// OIDC_<NAME>_* variables for each name listed in OIDC_PROVIDERS
+type OIDCProviderConfig struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	SubjectClaim string
+	EmailClaim   string
+	NameClaim    string
 }
 
 type TMDBConfig struct {
 	APIKey       string
 	BaseURL      string
 	ImageBaseURL string
+	CacheEnabled bool
 }
 
 type SessionConfig struct {
 	SecretKey string
 }
 
+// MetricsConfig holds /metrics configuration
+type MetricsConfig struct {
+	// Token, when set, must be sent by scrapers as a bearer token
+	Token string
+}
+
+// TracingConfig holds OpenTelemetry trace export configuration, read from the
+// standard OTEL_* variables
+type TracingConfig struct {
+	// Exporter is where spans are sent: otlp, stdout or none
+	Exporter string
+	// Endpoint is the base URL of the OTLP/HTTP collector
+	Endpoint string
+	// Headers are sent with every export, like collector API keys
+	Headers     map[string]string
+	ServiceName string
+	// SampleRatio is the fraction of new traces recorded, above 0 and up to 1
+	SampleRatio float64
+}
+
+// SecurityConfig holds the security headers sent with every response
+type SecurityConfig struct {
+	// ContentSecurityPolicy overrides the default policy, "off" disables it
+	ContentSecurityPolicy string
+	// HSTSMaxAge is the Strict-Transport-Security max-age in seconds, 0 disables it
+	HSTSMaxAge int
+}
+
+// RateLimitConfig holds the per-minute request budget of each rate limit policy
+type RateLimitConfig struct {
+	Enabled bool
+	// FailureMode is what happens while Redis is unavailable: local, open or closed
+	FailureMode string
+	Read        int
+	Write       int
+	TMDB        int
+	Account     int
+}
+
 // Load reads environment variables and returns a Config struct
 func Load() (*Config, error) {
 	// Load .env file if it exists (ignore error if not found)
@@ -58,9 +128,14 @@ func Load() (*Config, error) {
 
 	cfg := &Config{
 		Server: ServerConfig{
-			Env:  getEnv("NODE_ENV", "local"),
-			Port: getEnv("PORT", "4000"),
-			Host: getEnv("HOST", "http://localhost:4000"),
+			Env:      getEnv("NODE_ENV", "local"),
+			Port:     getEnv("PORT", "4000"),
+			Host:     getEnv("HOST", "http://localhost:4000"),
+			LogLevel: getEnv("LOG_LEVEL", "info"),
+			TrustedProxies: strings.FieldsFunc(getEnv("TRUSTED_PROXIES", ""), func(r rune) bool {
+				return r == ',' || r == ' '
+			}),
+			ForwardedHeader: http.CanonicalHeaderKey(getEnv("FORWARDED_HEADER", "X-Forwarded-For")),
 		},
 		Database: DatabaseConfig{
 			URL: getEnv("DATABASE_URL", ""),
@@ -82,10 +157,67 @@ func Load() (*Config, error) {
 			APIKey:       getEnv("TMDB_KEY", ""),
 			BaseURL:      getEnv("TMDB_URL", "https://api.themoviedb.org"),
 			ImageBaseURL: getEnv("TMDB_IMAGE_URL", "https://image.tmdb.org/t/p/w500"),
+			CacheEnabled: getEnv("TMDB_CACHE_ENABLED", "true") == "true",
 		},
 		Session: SessionConfig{
 			SecretKey: getEnv("SECRET_KEY", ""),
 		},
+		Metrics: MetricsConfig{
+			Token: getEnv("METRICS_TOKEN", ""),
+		},
+		Tracing: TracingConfig{
+			Exporter:    getEnv("OTEL_TRACES_EXPORTER", "none"),
+			Endpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+			Headers:     parseHeaders(getEnv("OTEL_EXPORTER_OTLP_HEADERS", "")),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "reelscore"),
+			SampleRatio: getEnvFloat("OTEL_TRACES_SAMPLER_ARG", 1),
+		},
+	}
+
+	// HSTS defaults to a year in production only, where the site is served over HTTPS
+	hstsDefault := 0
+	if cfg.IsProduction() {
+		hstsDefault = 365 * 24 * 60 * 60
+	}
+	hstsMaxAge, err := strconv.Atoi(getEnv("HSTS_MAX_AGE", strconv.Itoa(hstsDefault)))
+	if err != nil || hstsMaxAge < 0 {
+		return nil, fmt.Errorf("HSTS_MAX_AGE must be a number of seconds")
+	}
+	cfg.Security = SecurityConfig{
+		ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", ""),
+		HSTSMaxAge:            hstsMaxAge,
+	}
+
+	// Rate limiting defaults to on in production only, RATE_LIMIT_ENABLED overrides it
+	cfg.RateLimit = RateLimitConfig{
+		Enabled:     getEnv("RATE_LIMIT_ENABLED", strconv.FormatBool(cfg.IsProduction())) == "true",
+		FailureMode: getEnv("RATE_LIMIT_FAILURE_MODE", "local"),
+		Read:        getEnvInt("RATE_LIMIT_READ", 120),
+		Write:       getEnvInt("RATE_LIMIT_WRITE", 60),
+		TMDB:        getEnvInt("RATE_LIMIT_TMDB", 30),
+		Account:     getEnvInt("RATE_LIMIT_ACCOUNT", 20),
+	}
+
+	oidc, err := loadOIDCProviders(getEnv("OIDC_PROVIDERS", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.OAuth.OIDC = oidc
+
+	switch cfg.Server.ForwardedHeader {
+	case "X-Forwarded-For", "Forwarded":
+	default:
+		return nil, fmt.Errorf("FORWARDED_HEADER must be X-Forwarded-For or Forwarded")
+	}
+	switch cfg.RateLimit.FailureMode {
+	case "local", "open", "closed":
+	default:
+		return nil, fmt.Errorf("RATE_LIMIT_FAILURE_MODE must be local, open or closed")
+	}
+	switch cfg.Tracing.Exporter {
+	case "otlp", "stdout", "none":
+	default:
+		return nil, fmt.Errorf("OTEL_TRACES_EXPORTER must be otlp, stdout or none")
 	}
 
 	// Validate required fields
@@ -102,6 +234,48 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// oidcNamePattern restricts provider names to what is safe in routes and env var names
+var oidcNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// loadOIDCProviders reads the OpenID Connect providers named in a comma separated list
+func loadOIDCProviders(names string) ([]OIDCProviderConfig, error) {
+	var providers []OIDCProviderConfig
+	seen := make(map[string]bool)
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !oidcNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid OIDC provider name %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate OIDC provider %q", name)
+		}
+		seen[name] = true
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProviderConfig{
+			Name:         name,
+			DisplayName:  getEnv(prefix+"DISPLAY_NAME", name),
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(strings.ReplaceAll(getEnv(prefix+"SCOPES", "openid profile email"), ",", " ")),
+			SubjectClaim: getEnv(prefix+"SUBJECT_CLAIM", "sub"),
+			EmailClaim:   getEnv(prefix+"EMAIL_CLAIM", "email"),
+			NameClaim:    getEnv(prefix+"NAME_CLAIM", "name"),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -110,6 +284,41 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// getEnvInt reads a positive integer, falling back to the default when unset or invalid
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvFloat reads a fraction above 0 and up to 1, falling back to the default when unset or invalid
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || value <= 0 || value > 1 {
+		return defaultValue
+	}
+	return value
+}
+
+// parseHeaders parses comma separated key=value pairs, as in OTEL_EXPORTER_OTLP_HEADERS
+func parseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(strings.TrimSpace(val)); err == nil {
+			val = unescaped
+		}
+		headers[key] = val
+	}
+	return headers
+}
+
 // IsProduction returns true if running in production mode
 func (c *Config) IsProduction() bool {
 	return c.Server.Env == "production"
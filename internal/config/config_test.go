@@ -0,0 +1,62 @@
+package config
+
+import "testing"
+
+func TestLoadTMDBCacheEnabled(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"", true},
+		{"true", true},
+		{"false", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DATABASE_URL", "postgres://localhost/reelscore")
+			t.Setenv("SECRET_KEY", "0123456789abcdef0123456789abcdef")
+			t.Setenv("TMDB_CACHE_ENABLED", tt.value)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.TMDB.CacheEnabled != tt.want {
+				t.Errorf("CacheEnabled = %v, want %v", cfg.TMDB.CacheEnabled, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadForwardedHeader(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "X-Forwarded-For", false},
+		{"forwarded", "Forwarded", false},
+		{"X-Real-IP", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("DATABASE_URL", "postgres://localhost/reelscore")
+			t.Setenv("SECRET_KEY", "0123456789abcdef0123456789abcdef")
+			t.Setenv("FORWARDED_HEADER", tt.value)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Load() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Server.ForwardedHeader != tt.want {
+				t.Errorf("ForwardedHeader = %q, want %q", cfg.Server.ForwardedHeader, tt.want)
+			}
+		})
+	}
+}
@@ -52,6 +52,9 @@ func New(cfg Config) (*DB, error) {
 		poolConfig.MaxConnIdleTime = 30 * time.Minute
 	}
 
+	// Trace every query when tracing is enabled
+	poolConfig.ConnConfig.Tracer = queryTracer{}
+
 	// Create connection pool
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
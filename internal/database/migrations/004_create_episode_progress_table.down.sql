@@ -0,0 +1,2 @@
+-- Drop EpisodeProgress table
+DROP TABLE IF EXISTS "EpisodeProgress";
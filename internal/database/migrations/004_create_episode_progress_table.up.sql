@@ -0,0 +1,17 @@
+-- Create EpisodeProgress table
+CREATE TABLE "EpisodeProgress" (
+  "id" uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+  "serieId" uuid NOT NULL,
+  "userId" uuid NOT NULL,
+  "seasonNumber" integer NOT NULL,
+  "episodeNumber" integer NOT NULL,
+  "watchedAt" timestamp DEFAULT now() NOT NULL,
+  CONSTRAINT "EpisodeProgress_serieId_season_episode_unique" UNIQUE("serieId", "seasonNumber", "episodeNumber"),
+  CONSTRAINT "EpisodeProgress_serieId_Serie_id_fk" FOREIGN KEY ("serieId")
+    REFERENCES "Serie"("id") ON DELETE CASCADE,
+  CONSTRAINT "EpisodeProgress_userId_User_id_fk" FOREIGN KEY ("userId")
+    REFERENCES "User"("id") ON DELETE CASCADE
+);
+
+-- Create indexes for better query performance
+CREATE INDEX "idx_episode_progress_user_id" ON "EpisodeProgress"("userId");
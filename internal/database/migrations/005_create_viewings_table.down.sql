@@ -0,0 +1,2 @@
+-- Drop Viewing table
+DROP TABLE IF EXISTS "Viewing";
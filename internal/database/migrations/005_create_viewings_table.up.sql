@@ -0,0 +1,19 @@
+-- Create Viewing table
+CREATE TABLE "Viewing" (
+  "id" uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+  "movieId" uuid NOT NULL,
+  "userId" uuid NOT NULL,
+  "watchedOn" date NOT NULL,
+  "rating" numeric(3, 1),
+  "note" text,
+  "rewatch" boolean DEFAULT false NOT NULL,
+  "createdAt" timestamp DEFAULT now() NOT NULL,
+  CONSTRAINT "Viewing_movieId_Movie_id_fk" FOREIGN KEY ("movieId")
+    REFERENCES "Movie"("id") ON DELETE CASCADE,
+  CONSTRAINT "Viewing_userId_User_id_fk" FOREIGN KEY ("userId")
+    REFERENCES "User"("id") ON DELETE CASCADE
+);
+
+-- Create indexes for better query performance
+CREATE INDEX "idx_viewing_movie_id" ON "Viewing"("movieId");
+CREATE INDEX "idx_viewing_user_watched_on" ON "Viewing"("userId", "watchedOn");
@@ -0,0 +1,10 @@
+-- Enum values cannot be dropped, recreate the type without OIDC.
+-- This fails while OIDC users exist, delete or migrate them first.
+ALTER TYPE "Provider" RENAME TO "Provider_old";
+
+CREATE TYPE "Provider" AS ENUM ('GITHUB', 'GOOGLE');
+
+ALTER TABLE "User"
+  ALTER COLUMN "provider" TYPE "Provider" USING "provider"::text::"Provider";
+
+DROP TYPE "Provider_old";
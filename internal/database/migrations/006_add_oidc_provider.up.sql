@@ -0,0 +1,2 @@
+-- Allow users signed in through OpenID Connect providers
+ALTER TYPE "Provider" ADD VALUE IF NOT EXISTS 'OIDC';
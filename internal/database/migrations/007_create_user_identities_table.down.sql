@@ -0,0 +1,21 @@
+-- Restore the single-provider columns on User
+ALTER TABLE "User" ADD COLUMN "providerId" varchar(255);
+ALTER TABLE "User" ADD COLUMN "provider" "Provider";
+
+-- Keep each user's oldest identity, later linked identities cannot be represented
+UPDATE "User" u
+SET "providerId" = i."providerId", "provider" = i."provider"
+FROM (
+  SELECT DISTINCT ON ("userId") "userId", "provider", "providerId"
+  FROM "UserIdentity"
+  ORDER BY "userId", "createdAt" ASC
+) i
+WHERE i."userId" = u."id";
+
+ALTER TABLE "User" ALTER COLUMN "providerId" SET NOT NULL;
+ALTER TABLE "User" ALTER COLUMN "provider" SET NOT NULL;
+ALTER TABLE "User" ADD CONSTRAINT "User_providerId_key" UNIQUE ("providerId");
+CREATE INDEX "idx_user_provider_id" ON "User"("providerId");
+
+-- Drop UserIdentity table
+DROP TABLE IF EXISTS "UserIdentity";
@@ -0,0 +1,26 @@
+-- Create UserIdentity table so one user can sign in with several providers
+CREATE TABLE "UserIdentity" (
+  "id" uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+  "userId" uuid NOT NULL,
+  "provider" "Provider" NOT NULL,
+  "providerId" varchar(255) NOT NULL,
+  "email" varchar(255) NOT NULL DEFAULT '',
+  "createdAt" timestamp DEFAULT now() NOT NULL,
+  -- Subjects are only unique per provider
+  CONSTRAINT "UserIdentity_provider_providerId_unique" UNIQUE("provider", "providerId"),
+  CONSTRAINT "UserIdentity_userId_User_id_fk" FOREIGN KEY ("userId")
+    REFERENCES "User"("id") ON DELETE CASCADE
+);
+
+-- Create index on userId for listing a user's identities
+CREATE INDEX "idx_user_identity_user_id" ON "UserIdentity"("userId");
+
+-- Move every existing login over to its own identity
+INSERT INTO "UserIdentity" ("userId", "provider", "providerId", "email", "createdAt")
+SELECT "id", "provider", "providerId", "email", "createdAt"
+FROM "User";
+
+-- Drop the single-provider columns from User
+DROP INDEX IF EXISTS "idx_user_provider_id";
+ALTER TABLE "User" DROP COLUMN "providerId";
+ALTER TABLE "User" DROP COLUMN "provider";
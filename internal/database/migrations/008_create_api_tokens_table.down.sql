@@ -0,0 +1,2 @@
+-- Drop ApiToken table
+DROP TABLE IF EXISTS "ApiToken";
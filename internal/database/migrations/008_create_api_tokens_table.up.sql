@@ -0,0 +1,20 @@
+-- Create ApiToken table for personal access tokens
+CREATE TABLE "ApiToken" (
+  "id" uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+  "userId" uuid NOT NULL,
+  "name" varchar(100) NOT NULL,
+  -- SHA-256 of the token, the token itself is only shown once
+  "tokenHash" varchar(64) NOT NULL,
+  "prefix" varchar(16) NOT NULL,
+  "scopes" text[] NOT NULL,
+  "expiresAt" timestamp,
+  "lastUsedAt" timestamp,
+  "revokedAt" timestamp,
+  "createdAt" timestamp DEFAULT now() NOT NULL,
+  CONSTRAINT "ApiToken_tokenHash_unique" UNIQUE("tokenHash"),
+  CONSTRAINT "ApiToken_userId_User_id_fk" FOREIGN KEY ("userId")
+    REFERENCES "User"("id") ON DELETE CASCADE
+);
+
+-- Create index on userId for listing a user's tokens
+CREATE INDEX "idx_api_token_user_id" ON "ApiToken"("userId");
@@ -0,0 +1,3 @@
+-- Drop deletion schedule
+DROP INDEX IF EXISTS "idx_user_deletion_scheduled_at";
+ALTER TABLE "User" DROP COLUMN IF EXISTS "deletionScheduledAt";
@@ -0,0 +1,6 @@
+-- Accounts pending deletion are removed once the grace period ends
+ALTER TABLE "User" ADD COLUMN "deletionScheduledAt" timestamp;
+
+-- Create partial index for finding accounts due for deletion
+CREATE INDEX "idx_user_deletion_scheduled_at" ON "User"("deletionScheduledAt")
+  WHERE "deletionScheduledAt" IS NOT NULL;
@@ -3,12 +3,18 @@ package database
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -32,6 +38,11 @@ func NewRedisClient(cfg RedisConfig) (*RedisClient, error) {
 		Password: cfg.Password,
 		DB:       cfg.DB,
 	})
+	client.AddHook(metricsHook{})
+	// Arguments are left out of spans as they include session IDs
+	if err := redisotel.InstrumentTracing(client, redisotel.WithDBStatement(false)); err != nil {
+		return nil, fmt.Errorf("unable to trace Redis: %w", err)
+	}
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -89,49 +100,281 @@ func (s *SessionStore) GenerateSessionID() (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
-// Set stores a user ID in a session
-func (s *SessionStore) Set(ctx context.Context, sessionID string, userID uuid.UUID) error {
-	key := fmt.Sprintf("session:%s", sessionID)
-	return s.client.Set(ctx, key, userID.String(), s.ttl).Err()
+// ErrSessionNotFound is returned when a session does not exist or has expired
+var ErrSessionNotFound = errors.New("session not found")
+
+// lastSeenResolution limits how often a session's last-seen time is written
+const lastSeenResolution = time.Minute
+
+// Session holds a session's user and the device it was created on
+type Session struct {
+	// Handle identifies the session in the UI without exposing the session ID
+	Handle     string    `json:"-"`
+	UserID     uuid.UUID `json:"userId"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+}
+
+// SessionHandle derives a session's public handle from its ID
+func SessionHandle(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// sessionKey returns the Redis key of a session
+func sessionKey(sessionID string) string {
+	return fmt.Sprintf("session:%s", sessionID)
+}
+
+// userSessionsKey returns the Redis key of the set indexing a user's sessions
+func userSessionsKey(userID uuid.UUID) string {
+	return fmt.Sprintf("user_sessions:%s", userID)
+}
+
+// Set stores a new session for a user along with the device it was created on
+func (s *SessionStore) Set(ctx context.Context, sessionID string, userID uuid.UUID, ip, userAgent string) error {
+	now := time.Now().UTC()
+	data, err := json.Marshal(Session{
+		UserID:     userID,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		IP:         ip,
+		UserAgent:  userAgent,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, sessionKey(sessionID), data, s.ttl)
+	pipe.SAdd(ctx, userSessionsKey(userID), sessionID)
+	pipe.Expire(ctx, userSessionsKey(userID), s.ttl)
+	_, err = pipe.Exec(ctx)
+	return err
 }
 
 // Get retrieves a user ID from a session
 func (s *SessionStore) Get(ctx context.Context, sessionID string) (uuid.UUID, error) {
-	key := fmt.Sprintf("session:%s", sessionID)
+	key := sessionKey(sessionID)
 
-	val, err := s.client.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return uuid.Nil, fmt.Errorf("session not found")
-	}
+	session, err := s.load(ctx, sessionID)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to get session: %w", err)
+		return uuid.Nil, err
 	}
 
-	userID, err := uuid.Parse(val)
+	// Refresh TTL on access, recording last seen at most once per resolution
+	now := time.Now().UTC()
+	if now.Sub(session.LastSeenAt) >= lastSeenResolution {
+		session.LastSeenAt = now
+		if data, err := json.Marshal(session); err == nil {
+			s.client.Set(ctx, key, data, s.ttl)
+			// Also indexes sessions created before the per-user index existed
+			s.client.SAdd(ctx, userSessionsKey(session.UserID), sessionID)
+		}
+	} else {
+		s.client.Expire(ctx, key, s.ttl)
+	}
+	s.client.Expire(ctx, userSessionsKey(session.UserID), s.ttl)
+
+	return session.UserID, nil
+}
+
+// List returns a user's active sessions, most recently seen first, pruning expired
+// sessions from the index
+func (s *SessionStore) List(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	ids, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID in session: %w", err)
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	sessions := []Session{}
+	for _, id := range ids {
+		session, err := s.load(ctx, id)
+		if errors.Is(err, ErrSessionNotFound) {
+			s.client.SRem(ctx, userSessionsKey(userID), id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if session.UserID != userID {
+			continue
+		}
+		session.Handle = SessionHandle(id)
+		sessions = append(sessions, *session)
 	}
 
-	// Refresh TTL on access
-	s.client.Expire(ctx, key, s.ttl)
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
 
-	return userID, nil
+	return sessions, nil
 }
 
 // Delete removes a session
 func (s *SessionStore) Delete(ctx context.Context, sessionID string) error {
-	key := fmt.Sprintf("session:%s", sessionID)
-	return s.client.Del(ctx, key).Err()
+	session, err := s.load(ctx, sessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, sessionKey(sessionID))
+	pipe.SRem(ctx, userSessionsKey(session.UserID), sessionID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// DeleteByHandle removes one of a user's sessions by its public handle
+func (s *SessionStore) DeleteByHandle(ctx context.Context, userID uuid.UUID, handle string) error {
+	ids, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	for _, id := range ids {
+		if SessionHandle(id) == handle {
+			pipe := s.client.TxPipeline()
+			pipe.Del(ctx, sessionKey(id))
+			pipe.SRem(ctx, userSessionsKey(userID), id)
+			_, err := pipe.Exec(ctx)
+			return err
+		}
+	}
+
+	return ErrSessionNotFound
+}
+
+// DeleteUser removes every session of a user, logging them out everywhere
+func (s *SessionStore) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	ids, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	keys := []string{userSessionsKey(userID)}
+	for _, id := range ids {
+		keys = append(keys, sessionKey(id))
+	}
+	return s.client.Del(ctx, keys...).Err()
+}
+
+// load reads a session. Sessions created before metadata was stored hold only the
+// user ID and are read with zero metadata.
+func (s *SessionStore) load(ctx context.Context, sessionID string) (*Session, error) {
+	val, err := s.client.Get(ctx, sessionKey(sessionID)).Result()
+	if err == redis.Nil {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal([]byte(val), &session); err != nil {
+		userID, parseErr := uuid.Parse(val)
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid session: %w", err)
+		}
+		session.UserID = userID
+	}
+
+	return &session, nil
+}
+
+// Count returns the number of sessions, scanning the keyspace
+func (s *SessionStore) Count(ctx context.Context) (int, error) {
+	count := 0
+	iter := s.client.Scan(ctx, 0, "session:*", 1000).Iterator()
+	for iter.Next(ctx) {
+		count++
+	}
+	if err := iter.Err(); err != nil {
+		return 0, fmt.Errorf("failed to count sessions: %w", err)
+	}
+
+	return count, nil
 }
 
 // Exists checks if a session exists
 func (s *SessionStore) Exists(ctx context.Context, sessionID string) (bool, error) {
-	key := fmt.Sprintf("session:%s", sessionID)
-
-	result, err := s.client.Exists(ctx, key).Result()
+	result, err := s.client.Exists(ctx, sessionKey(sessionID)).Result()
 	if err != nil {
 		return false, fmt.Errorf("failed to check session existence: %w", err)
 	}
 
 	return result > 0, nil
 }
+
+// ErrOAuthStateNotFound is returned when a login attempt is unknown, expired or already used
+var ErrOAuthStateNotFound = errors.New("oauth state not found")
+
+// OAuthState is the server-side half of an in-flight OAuth login
+type OAuthState struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce,omitempty"`
+	ReturnTo string `json:"returnTo"`
+	// LinkUserID is set when a signed-in user links another provider to their account
+	LinkUserID string `json:"linkUserId,omitempty"`
+}
+
+// OAuthStateStore keeps OAuth state and PKCE verifiers in Redis between the login
+// redirect and the provider callback
+type OAuthStateStore struct {
+	client *RedisClient
+	ttl    time.Duration
+}
+
+// NewOAuthStateStore creates a new OAuth state store
+func NewOAuthStateStore(client *RedisClient, ttl time.Duration) *OAuthStateStore {
+	if ttl == 0 {
+		ttl = 10 * time.Minute // default 10 minutes
+	}
+	return &OAuthStateStore{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// TTL returns how long a login attempt stays valid
+func (s *OAuthStateStore) TTL() time.Duration {
+	return s.ttl
+}
+
+// Save stores the state of a login attempt under its pre-login ID
+func (s *OAuthStateStore) Save(ctx context.Context, loginID string, state OAuthState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode oauth state: %w", err)
+	}
+
+	key := fmt.Sprintf("oauth_state:%s", loginID)
+	return s.client.Set(ctx, key, data, s.ttl).Err()
+}
+
+// Consume retrieves and deletes the state of a login attempt so it can only be used once
+func (s *OAuthStateStore) Consume(ctx context.Context, loginID string) (*OAuthState, error) {
+	key := fmt.Sprintf("oauth_state:%s", loginID)
+
+	val, err := s.client.GetDel(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrOAuthStateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth state: %w", err)
+	}
+
+	var state OAuthState
+	if err := json.Unmarshal(val, &state); err != nil {
+		return nil, fmt.Errorf("invalid oauth state: %w", err)
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// testRedis connects to the Redis at REDIS_ADDR, skipping the test when it is unset
+func testRedis(t *testing.T) *RedisClient {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR not set, skipping Redis test")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("failed to connect to Redis at %s: %v", addr, err)
+	}
+	return &RedisClient{Client: client}
+}
+
+// testSessions creates a session for each device of a new user and deletes them when
+// the test ends
+func testSessions(t *testing.T, store *SessionStore, devices ...string) (uuid.UUID, []string) {
+	t.Helper()
+
+	ctx := context.Background()
+	userID := uuid.New()
+	var ids []string
+	for _, device := range devices {
+		id, err := store.GenerateSessionID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Set(ctx, id, userID, "192.0.2.1", device); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	t.Cleanup(func() { store.DeleteUser(context.Background(), userID) })
+	return userID, ids
+}
+
+// assertSessionExists checks whether a session is still stored
+func assertSessionExists(t *testing.T, store *SessionStore, sessionID string, want bool) {
+	t.Helper()
+
+	got, err := store.Exists(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("Exists: %v", err)
+	}
+	if got != want {
+		t.Errorf("session %s exists = %v, want %v", SessionHandle(sessionID), got, want)
+	}
+}
+
+func TestSessionStoreDeleteByHandle(t *testing.T) {
+	store := NewSessionStore(testRedis(t), time.Hour)
+	ctx := context.Background()
+	userID, ids := testSessions(t, store, "laptop", "phone")
+	_, others := testSessions(t, store, "tablet")
+
+	// Revoking one device leaves the others signed in
+	if err := store.DeleteByHandle(ctx, userID, SessionHandle(ids[0])); err != nil {
+		t.Fatalf("DeleteByHandle: %v", err)
+	}
+	assertSessionExists(t, store, ids[0], false)
+	assertSessionExists(t, store, ids[1], true)
+
+	sessions, err := store.List(ctx, userID)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].UserAgent != "phone" {
+		t.Errorf("List() = %+v, want only the phone", sessions)
+	}
+
+	// Another user's session and unknown handles aren't found
+	if err := store.DeleteByHandle(ctx, userID, SessionHandle(others[0])); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("DeleteByHandle(other user's session) error = %v, want ErrSessionNotFound", err)
+	}
+	assertSessionExists(t, store, others[0], true)
+	if err := store.DeleteByHandle(ctx, userID, SessionHandle(ids[0])); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("DeleteByHandle(revoked session) error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestSessionStoreDeleteUser(t *testing.T) {
+	client := testRedis(t)
+	store := NewSessionStore(client, time.Hour)
+	ctx := context.Background()
+	userID, ids := testSessions(t, store, "laptop", "phone")
+	_, others := testSessions(t, store, "tablet")
+
+	// Signing out everywhere removes every session and the index
+	if err := store.DeleteUser(ctx, userID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	for _, id := range ids {
+		assertSessionExists(t, store, id, false)
+	}
+	if n, err := client.Exists(ctx, userSessionsKey(userID)).Result(); err != nil || n != 0 {
+		t.Errorf("index still exists after DeleteUser (n=%d, err=%v)", n, err)
+	}
+	if _, err := store.Get(ctx, ids[0]); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Get() error = %v, want ErrSessionNotFound", err)
+	}
+
+	// Other users stay signed in
+	assertSessionExists(t, store, others[0], true)
+}
+
+func TestSessionStoreCount(t *testing.T) {
+	store := NewSessionStore(testRedis(t), time.Hour)
+	ctx := context.Background()
+
+	before, err := store.Count(ctx)
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	_, ids := testSessions(t, store, "laptop", "phone")
+	if got, err := store.Count(ctx); err != nil || got != before+2 {
+		t.Errorf("Count() = %d, %v, want %d", got, err, before+2)
+	}
+
+	if err := store.Delete(ctx, ids[0]); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got, err := store.Count(ctx); err != nil || got != before+1 {
+		t.Errorf("Count() after Delete = %d, %v, want %d", got, err, before+1)
+	}
+}
+
+func TestSessionStoreIndexDropsExpiredSessions(t *testing.T) {
+	client := testRedis(t)
+	store := NewSessionStore(client, time.Hour)
+	ctx := context.Background()
+	userID, ids := testSessions(t, store, "laptop", "phone")
+
+	// The index lives as long as the sessions it holds
+	ttl, err := client.TTL(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		t.Fatalf("TTL: %v", err)
+	}
+	if ttl <= 0 || ttl > time.Hour {
+		t.Errorf("index TTL = %v, want up to the session TTL", ttl)
+	}
+
+	// The laptop's session expires, leaving its ID in the index
+	if err := client.Del(ctx, sessionKey(ids[0])).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := store.List(ctx, userID)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Handle != SessionHandle(ids[1]) {
+		t.Errorf("List() = %+v, want only the phone", sessions)
+	}
+	if member, err := client.SIsMember(ctx, userSessionsKey(userID), ids[0]).Result(); err != nil || member {
+		t.Errorf("expired session still indexed (member=%v, err=%v)", member, err)
+	}
+}
@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/liamwears/reelscore/internal/apperror"
+	"github.com/liamwears/reelscore/internal/database"
+	"github.com/liamwears/reelscore/internal/middleware"
+	"github.com/liamwears/reelscore/internal/models"
+	"github.com/liamwears/reelscore/internal/services"
+)
+
+// AccountHandler handles account settings requests
+type AccountHandler struct {
+	userService    *services.UserService
+	tokenService   *services.APITokenService
+	exportService  *services.ExportService
+	sessionStore   *database.SessionStore
+	authMiddleware *middleware.AuthMiddleware
+	oidcProviders  []*services.OIDCProvider
+	renderer       *Renderer
+	logger         *slog.Logger
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(
+	userService *services.UserService,
+	tokenService *services.APITokenService,
+	exportService *services.ExportService,
+	sessionStore *database.SessionStore,
+	authMiddleware *middleware.AuthMiddleware,
+	oidcProviders []*services.OIDCProvider,
+	renderer *Renderer,
+	logger *slog.Logger,
+) *AccountHandler {
+	return &AccountHandler{
+		userService:    userService,
+		tokenService:   tokenService,
+		exportService:  exportService,
+		sessionStore:   sessionStore,
+		authMiddleware: authMiddleware,
+		oidcProviders:  oidcProviders,
+		renderer:       renderer,
+		logger:         logger,
+	}
+}
+
+// linkableProvider is a login provider shown on the account page
+type linkableProvider struct {
+	Name     string
+	Label    string
+	LoginURL string
+	Linked   bool
+}
+
+// Settings handles GET /account
+func (h *AccountHandler) Settings(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	var message, errorMessage string
+	if r.URL.Query().Get("linked") == "true" {
+		message = "Login provider linked"
+	}
+	if r.URL.Query().Get("error") == "identity_in_use" {
+		errorMessage = "That account is already linked to another ReelScore user"
+	}
+	switch r.URL.Query().Get("deletion") {
+	case "scheduled":
+		message = "Your account is scheduled for deletion"
+	case "cancelled":
+		message = "Account deletion cancelled"
+	}
+
+	h.render(w, r, user, map[string]interface{}{
+		"Message": message,
+		"Error":   errorMessage,
+	})
+}
+
+// CreateToken handles POST /account/tokens from the account page form
+func (h *AccountHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	input := models.CreateAPITokenInput{Name: r.PostForm.Get("name")}
+	for _, scope := range r.PostForm["scopes"] {
+		input.Scopes = append(input.Scopes, models.TokenScope(scope))
+	}
+	if days, err := strconv.Atoi(r.PostForm.Get("expiresInDays")); err == nil {
+		input.ExpiresInDays = &days
+	}
+
+	token, err := h.tokenService.Create(r.Context(), user.ID, input)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPITokenInput) {
+			h.render(w, r, user, map[string]interface{}{"Error": err.Error()})
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to create api token", "error", err)
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	// The secret is shown on this response only
+	w.Header().Set("Cache-Control", "no-store")
+	h.render(w, r, user, map[string]interface{}{"NewToken": token})
+}
+
+// DownloadData handles GET /account/data, a zip of everything held about the user
+func (h *AccountHandler) DownloadData(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	identities, err := h.userService.Identities(r.Context(), user.ID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list identities", "error", err)
+		http.Error(w, "Failed to export data", http.StatusInternalServerError)
+		return
+	}
+	sessions, err := h.sessionStore.List(r.Context(), user.ID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list sessions", "error", err)
+		http.Error(w, "Failed to export data", http.StatusInternalServerError)
+		return
+	}
+	tokens, err := h.tokenService.List(r.Context(), user.ID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list api tokens", "error", err)
+		http.Error(w, "Failed to export data", http.StatusInternalServerError)
+		return
+	}
+
+	// Large libraries can outlast the server write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportTimeout))
+
+	filename := fmt.Sprintf("reelscore-data-%s.zip", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Cache-Control", "no-store")
+
+	records := map[string]interface{}{
+		"profile":    user,
+		"identities": identities,
+		"sessions":   sessions,
+		"api_tokens": tokens,
+	}
+
+	// The zip is streamed, so an error part way through can only be logged
+	if err := h.exportService.Archive(r.Context(), user.ID, records, w); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to export account data", "error", err)
+	}
+}
+
+// ScheduleDeletion handles POST /account/delete. The user confirms by typing their
+// email address, and the account is deleted once the grace period ends.
+func (h *AccountHandler) ScheduleDeletion(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(r.PostForm.Get("confirm")), user.Email) {
+		h.render(w, r, user, map[string]interface{}{"Error": "Type your email address to confirm deleting your account"})
+		return
+	}
+
+	if _, err := h.userService.ScheduleDeletion(r.Context(), user.ID); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to schedule account deletion", "error", err)
+		http.Error(w, "Failed to schedule deletion", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/account?deletion=scheduled", http.StatusSeeOther)
+}
+
+// CancelDeletion handles POST /account/delete/cancel
+func (h *AccountHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.userService.CancelDeletion(r.Context(), user.ID); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to cancel account deletion", "error", err)
+		http.Error(w, "Failed to cancel deletion", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/account?deletion=cancelled", http.StatusSeeOther)
+}
+
+// render renders the account page with its identities and tokens plus extra data
+func (h *AccountHandler) render(w http.ResponseWriter, r *http.Request, user *models.User, extra map[string]interface{}) {
+	identities, err := h.userService.Identities(r.Context(), user.ID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list identities", "error", err)
+		http.Error(w, "Failed to load account", http.StatusInternalServerError)
+		return
+	}
+
+	// Every configured provider, marking the ones already linked
+	linked := make(map[string]bool, len(identities))
+	for _, identity := range identities {
+		linked[identity.ProviderName()] = true
+	}
+	providers := []linkableProvider{
+		{Name: models.ProviderGitHub.String(), Label: "GitHub", LoginURL: "/auth/github/login?link=true"},
+		{Name: models.ProviderGoogle.String(), Label: "Google", LoginURL: "/auth/google/login?link=true"},
+	}
+	for _, provider := range h.oidcProviders {
+		providers = append(providers, linkableProvider{
+			Name:     provider.Name(),
+			Label:    provider.DisplayName(),
+			LoginURL: "/auth/oidc/" + provider.Name() + "/login?link=true",
+		})
+	}
+	labels := make(map[string]string, len(providers))
+	for i := range providers {
+		providers[i].Linked = linked[providers[i].Name]
+		labels[providers[i].Name] = providers[i].Label
+	}
+
+	tokens, err := h.tokenService.List(r.Context(), user.ID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list api tokens", "error", err)
+		http.Error(w, "Failed to load account", http.StatusInternalServerError)
+		return
+	}
+
+	// Render template
+	data := map[string]interface{}{
+		"User":       user,
+		"ActivePage": "account",
+		"Identities": identities,
+		"Labels":     labels,
+		"Providers":  providers,
+		"Tokens":     tokens,
+		"Scopes":     []models.TokenScope{models.ScopeLibraryRead, models.ScopeLibraryWrite},
+		"GraceDays":  int(services.AccountDeletionGracePeriod.Hours() / 24),
+	}
+	for key, value := range extra {
+		data[key] = value
+	}
+
+	h.renderer.RenderPage(w, r, "account.html", data)
+}
+
+// UnlinkIdentity handles DELETE /api/account/identities/{id}
+func (h *AccountHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Get identity ID from path
+	identityID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid identity ID"))
+		return
+	}
+
+	// Call service
+	err = h.userService.UnlinkIdentity(r.Context(), userID, identityID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			apperror.Write(w, r, apperror.NotFound("Identity not found"))
+			return
+		}
+		if errors.Is(err, services.ErrLastIdentity) {
+			apperror.Write(w, r, apperror.Conflict("You can't unlink your only login provider"))
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to unlink identity", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to unlink provider"))
+		return
+	}
+
+	// Return success
+	w.Header().Set("HX-Trigger", `{"showMessage":"Login provider unlinked"}`)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// device is a session shown on the devices page
+type device struct {
+	database.Session
+	Name    string
+	Current bool
+}
+
+// Devices handles GET /account/devices
+func (h *AccountHandler) Devices(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	sessions, err := h.sessionStore.List(r.Context(), user.ID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list sessions", "error", err)
+		http.Error(w, "Failed to load devices", http.StatusInternalServerError)
+		return
+	}
+
+	currentHandle := ""
+	if sessionID, ok := middleware.GetSessionIDFromContext(r.Context()); ok {
+		currentHandle = database.SessionHandle(sessionID)
+	}
+
+	devices := make([]device, 0, len(sessions))
+	for _, session := range sessions {
+		devices = append(devices, device{
+			Session: session,
+			Name:    deviceName(session.UserAgent),
+			Current: session.Handle == currentHandle,
+		})
+	}
+
+	// Render template
+	data := map[string]interface{}{
+		"User":       user,
+		"ActivePage": "account",
+		"Devices":    devices,
+	}
+
+	h.renderer.RenderPage(w, r, "devices.html", data)
+}
+
+// RevokeSession handles DELETE /api/sessions/{handle}
+func (h *AccountHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Call store
+	err := h.sessionStore.DeleteByHandle(r.Context(), userID, r.PathValue("handle"))
+	if err != nil {
+		if errors.Is(err, database.ErrSessionNotFound) {
+			apperror.Write(w, r, apperror.NotFound("Session not found"))
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to revoke session", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to sign out device"))
+		return
+	}
+
+	// Return success
+	w.Header().Set("HX-Trigger", `{"showMessage":"Device signed out"}`)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutEverywhere handles DELETE /api/sessions, ending every session of the user
+// including the current one
+func (h *AccountHandler) LogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	if err := h.sessionStore.DeleteUser(r.Context(), userID); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to delete sessions", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to sign out devices"))
+		return
+	}
+
+	h.authMiddleware.ClearSessionCookie(w)
+	w.Header().Set("HX-Redirect", "/login")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deviceName gives a rough browser and OS description of a user agent
+func deviceName(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	}
+
+	platform := "unknown OS"
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		platform = "iOS"
+	case strings.Contains(userAgent, "Android"):
+		platform = "Android"
+	case strings.Contains(userAgent, "Windows"):
+		platform = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"):
+		platform = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		platform = "Linux"
+	}
+
+	return browser + " on " + platform
+}
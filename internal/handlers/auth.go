@@ -1,11 +1,14 @@
 package handlers
 
 import (
-	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/github"
@@ -21,11 +24,14 @@ import (
 type AuthHandler struct {
 	userService    *services.UserService
 	sessionStore   *database.SessionStore
+	stateStore     *database.OAuthStateStore
 	authMiddleware *middleware.AuthMiddleware
 	googleConfig   *oauth2.Config
 	githubConfig   *oauth2.Config
+	oidcProviders  map[string]*services.OIDCProvider
+	oidcOrder      []*services.OIDCProvider
 	renderer       *Renderer
-	logger         *log.Logger
+	logger         *slog.Logger
 }
 
 // AuthConfig holds authentication configuration
@@ -35,16 +41,18 @@ type AuthConfig struct {
 	GitHubClientID     string
 	GitHubClientSecret string
 	CallbackHost       string
+	OIDCProviders      []*services.OIDCProvider
 }
 
 // NewAuthHandler creates a new auth handler
 func NewAuthHandler(
 	userService *services.UserService,
 	sessionStore *database.SessionStore,
+	stateStore *database.OAuthStateStore,
 	authMiddleware *middleware.AuthMiddleware,
 	renderer *Renderer,
 	cfg AuthConfig,
-	logger *log.Logger,
+	logger *slog.Logger,
 ) *AuthHandler {
 	ghConfig := &oauth2.Config{
 		ClientID:     cfg.GitHubClientID,
@@ -63,46 +71,48 @@ func NewAuthHandler(
 	}
 
 	// Log the constructed callback URL for debugging
-	logger.Printf("Google OAuth Callback URL: %s", googleConfig.RedirectURL)
+	logger.Info("Google OAuth callback URL", "url", googleConfig.RedirectURL)
+
+	oidcProviders := make(map[string]*services.OIDCProvider, len(cfg.OIDCProviders))
+	for _, provider := range cfg.OIDCProviders {
+		oidcProviders[provider.Name()] = provider
+	}
 
 	return &AuthHandler{
 		userService:    userService,
 		sessionStore:   sessionStore,
+		stateStore:     stateStore,
 		authMiddleware: authMiddleware,
 		renderer:       renderer,
 		logger:         logger,
 		googleConfig:   googleConfig,
 		githubConfig:   ghConfig,
+		oidcProviders:  oidcProviders,
+		oidcOrder:      cfg.OIDCProviders,
 	}
 }
 
 // Login displays the login page
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	h.renderer.RenderPage(w, "login.html", nil)
+	data := map[string]interface{}{
+		"ReturnTo":      r.URL.Query().Get("return_to"),
+		"OIDCProviders": h.oidcOrder,
+	}
+	h.renderer.RenderPage(w, r, "login.html", data)
 }
 
 // GoogleLogin initiates Google OAuth flow
 func (h *AuthHandler) GoogleLogin(w http.ResponseWriter, r *http.Request) {
-	// Generate state token for CSRF protection
-	state, err := h.sessionStore.GenerateSessionID()
-	if err != nil {
-		h.logger.Printf("Failed to generate state token: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Store state in session temporarily (5 minutes)
-	// In production, you might want to use a separate cache for state tokens
-	ctx := context.WithValue(r.Context(), "oauth_state", state)
-
-	// Redirect to Google
-	url := h.googleConfig.AuthCodeURL(state, oauth2.AccessTypeOffline)
-	http.Redirect(w, r.WithContext(ctx), url, http.StatusTemporaryRedirect)
+	h.beginLogin(w, r, models.ProviderGoogle.String(), "", h.googleConfig, oauth2.AccessTypeOffline)
 }
 
 // GoogleCallback handles Google OAuth callback
 func (h *AuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
-	// Verify state (simplified - in production use proper state validation)
+	loginState, ok := h.verifyLogin(w, r, models.ProviderGoogle.String())
+	if !ok {
+		return
+	}
+
 	code := r.URL.Query().Get("code")
 	if code == "" {
 		http.Error(w, "No code provided", http.StatusBadRequest)
@@ -110,9 +120,9 @@ func (h *AuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Exchange code for token
-	token, err := h.googleConfig.Exchange(r.Context(), code)
+	token, err := h.googleConfig.Exchange(r.Context(), code, oauth2.VerifierOption(loginState.Verifier))
 	if err != nil {
-		h.logger.Printf("Failed to exchange code: %v", err)
+		h.logger.ErrorContext(r.Context(), "Failed to exchange code", "error", err)
 		http.Error(w, "Failed to exchange code", http.StatusInternalServerError)
 		return
 	}
@@ -121,7 +131,7 @@ func (h *AuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 	client := h.googleConfig.Client(r.Context(), token)
 	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
 	if err != nil {
-		h.logger.Printf("Failed to get user info: %v", err)
+		h.logger.ErrorContext(r.Context(), "Failed to get user info", "error", err)
 		http.Error(w, "Failed to get user info", http.StatusInternalServerError)
 		return
 	}
@@ -133,64 +143,26 @@ func (h *AuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 		Name  string `json:"name"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		h.logger.Printf("Failed to decode user info: %v", err)
+		h.logger.ErrorContext(r.Context(), "Failed to decode user info", "error", err)
 		http.Error(w, "Failed to decode user info", http.StatusInternalServerError)
 		return
 	}
 
-	// Find or create user
-	user, err := h.userService.FindOrCreate(
-		r.Context(),
-		userInfo.ID,
-		models.ProviderGoogle,
-		userInfo.Email,
-		userInfo.Name,
-	)
-	if err != nil {
-		h.logger.Printf("Failed to find or create user: %v", err)
-		http.Error(w, "Failed to create user", http.StatusInternalServerError)
-		return
-	}
-
-	// Create session
-	sessionID, err := h.sessionStore.GenerateSessionID()
-	if err != nil {
-		h.logger.Printf("Failed to generate session ID: %v", err)
-		http.Error(w, "Failed to create session", http.StatusInternalServerError)
-		return
-	}
-
-	if err := h.sessionStore.Set(r.Context(), sessionID, user.ID); err != nil {
-		h.logger.Printf("Failed to store session: %v", err)
-		http.Error(w, "Failed to store session", http.StatusInternalServerError)
-		return
-	}
-
-	// Set cookie
-	h.authMiddleware.SetSessionCookie(w, sessionID)
-
-	// Redirect to movies page
-	http.Redirect(w, r, "/movies", http.StatusSeeOther)
+	h.finishLogin(w, r, loginState, models.ProviderGoogle, userInfo.ID, userInfo.Email, userInfo.Name)
 }
 
 // GitHubLogin initiates GitHub OAuth flow
 func (h *AuthHandler) GitHubLogin(w http.ResponseWriter, r *http.Request) {
-	// Generate state token for CSRF protection
-	state, err := h.sessionStore.GenerateSessionID()
-	if err != nil {
-		h.logger.Printf("Failed to generate state token: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Redirect to GitHub
-	url := h.githubConfig.AuthCodeURL(state)
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+	h.beginLogin(w, r, models.ProviderGitHub.String(), "", h.githubConfig)
 }
 
 // GitHubCallback handles GitHub OAuth callback
 func (h *AuthHandler) GitHubCallback(w http.ResponseWriter, r *http.Request) {
-	// Verify state (simplified - in production use proper state validation)
+	loginState, ok := h.verifyLogin(w, r, models.ProviderGitHub.String())
+	if !ok {
+		return
+	}
+
 	code := r.URL.Query().Get("code")
 	if code == "" {
 		http.Error(w, "No code provided", http.StatusBadRequest)
@@ -198,9 +170,9 @@ func (h *AuthHandler) GitHubCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Exchange code for token
-	token, err := h.githubConfig.Exchange(r.Context(), code)
+	token, err := h.githubConfig.Exchange(r.Context(), code, oauth2.VerifierOption(loginState.Verifier))
 	if err != nil {
-		h.logger.Printf("Failed to exchange code: %v", err)
+		h.logger.ErrorContext(r.Context(), "Failed to exchange code", "error", err)
 		http.Error(w, "Failed to exchange code", http.StatusInternalServerError)
 		return
 	}
@@ -209,7 +181,7 @@ func (h *AuthHandler) GitHubCallback(w http.ResponseWriter, r *http.Request) {
 	client := h.githubConfig.Client(r.Context(), token)
 	resp, err := client.Get("https://api.github.com/user")
 	if err != nil {
-		h.logger.Printf("Failed to get user info: %v", err)
+		h.logger.ErrorContext(r.Context(), "Failed to get user info", "error", err)
 		http.Error(w, "Failed to get user info", http.StatusInternalServerError)
 		return
 	}
@@ -222,7 +194,7 @@ func (h *AuthHandler) GitHubCallback(w http.ResponseWriter, r *http.Request) {
 		Login string `json:"login"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		h.logger.Printf("Failed to decode user info: %v", err)
+		h.logger.ErrorContext(r.Context(), "Failed to decode user info", "error", err)
 		http.Error(w, "Failed to decode user info", http.StatusInternalServerError)
 		return
 	}
@@ -252,39 +224,67 @@ func (h *AuthHandler) GitHubCallback(w http.ResponseWriter, r *http.Request) {
 		userInfo.Name = userInfo.Login
 	}
 
-	// Find or create user
-	user, err := h.userService.FindOrCreate(
-		r.Context(),
-		fmt.Sprintf("%d", userInfo.ID),
-		models.ProviderGitHub,
-		userInfo.Email,
-		userInfo.Name,
-	)
+	h.finishLogin(w, r, loginState, models.ProviderGitHub, fmt.Sprintf("%d", userInfo.ID), userInfo.Email, userInfo.Name)
+}
+
+// OIDCLogin initiates an OpenID Connect flow for the provider named in the path
+func (h *AuthHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.oidcProviders[r.PathValue("name")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	config, err := provider.OAuth2Config(r.Context())
 	if err != nil {
-		h.logger.Printf("Failed to find or create user: %v", err)
-		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Failed to load OIDC provider", "provider", provider.Name(), "error", err)
+		http.Error(w, "Login provider unavailable", http.StatusServiceUnavailable)
 		return
 	}
 
-	// Create session
-	sessionID, err := h.sessionStore.GenerateSessionID()
+	// The nonce binds the ID token to this login attempt
+	nonce, err := h.sessionStore.GenerateSessionID()
 	if err != nil {
-		h.logger.Printf("Failed to generate session ID: %v", err)
-		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Failed to generate nonce", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	if err := h.sessionStore.Set(r.Context(), sessionID, user.ID); err != nil {
-		h.logger.Printf("Failed to store session: %v", err)
-		http.Error(w, "Failed to store session", http.StatusInternalServerError)
+	h.beginLogin(w, r, oidcStateProvider(provider.Name()), nonce, config, oauth2.SetAuthURLParam("nonce", nonce))
+}
+
+// OIDCCallback handles an OpenID Connect callback
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.oidcProviders[r.PathValue("name")]
+	if !ok {
+		http.NotFound(w, r)
 		return
 	}
 
-	// Set cookie
-	h.authMiddleware.SetSessionCookie(w, sessionID)
+	loginState, ok := h.verifyLogin(w, r, oidcStateProvider(provider.Name()))
+	if !ok {
+		return
+	}
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "No code provided", http.StatusBadRequest)
+		return
+	}
 
-	// Redirect to movies page
-	http.Redirect(w, r, "/movies", http.StatusSeeOther)
+	// Exchange code and verify the ID token
+	identity, err := provider.Authenticate(r.Context(), code, loginState.Verifier, loginState.Nonce)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to authenticate with OIDC provider", "provider", provider.Name(), "error", err)
+		if errors.Is(err, services.ErrInvalidIDToken) || errors.Is(err, services.ErrMissingClaim) {
+			http.Error(w, "Invalid identity token", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to authenticate", http.StatusBadGateway)
+		return
+	}
+
+	h.finishLogin(w, r, loginState, models.ProviderOIDC, models.OIDCProviderID(provider.Name(), identity.Subject), identity.Email, identity.Name)
 }
 
 // Logout handles user logout
@@ -302,3 +302,166 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	// Redirect to login
 	http.Redirect(w, r, "/login", http.StatusSeeOther)
 }
+
+// beginLogin stores a fresh state and PKCE verifier bound to a pre-login cookie and
+// redirects to the provider
+func (h *AuthHandler) beginLogin(w http.ResponseWriter, r *http.Request, provider, nonce string, config *oauth2.Config, opts ...oauth2.AuthCodeOption) {
+	// Generate state token for CSRF protection and the ID of this login attempt
+	state, err := h.sessionStore.GenerateSessionID()
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to generate state token", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	loginID, err := h.sessionStore.GenerateSessionID()
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to generate login ID", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	linkUserID := ""
+	if userID, ok := middleware.GetUserIDFromContext(r.Context()); ok && r.URL.Query().Get("link") == "true" {
+		linkUserID = userID.String()
+	}
+
+	verifier := oauth2.GenerateVerifier()
+	err = h.stateStore.Save(r.Context(), loginID, database.OAuthState{
+		Provider: provider,
+		State:    state,
+		Verifier: verifier,
+		Nonce:    nonce,
+		ReturnTo: safeReturnTo(r.URL.Query().Get("return_to")),
+		// Signed-in users come here from account settings to link another provider
+		LinkUserID: linkUserID,
+	})
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to store oauth state", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.authMiddleware.SetLoginCookie(w, loginID, h.stateStore.TTL())
+
+	// Redirect to the provider
+	opts = append(opts, oauth2.S256ChallengeOption(verifier))
+	http.Redirect(w, r, config.AuthCodeURL(state, opts...), http.StatusTemporaryRedirect)
+}
+
+// verifyLogin consumes the login attempt bound to the pre-login cookie and checks it
+// against the callback's state. It writes the error response when verification fails.
+func (h *AuthHandler) verifyLogin(w http.ResponseWriter, r *http.Request, provider string) (*database.OAuthState, bool) {
+	loginID, ok := h.authMiddleware.GetLoginCookie(r)
+	if !ok {
+		http.Error(w, "Login attempt expired, please sign in again", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Each login attempt can only be used once
+	h.authMiddleware.ClearLoginCookie(w)
+	loginState, err := h.stateStore.Consume(r.Context(), loginID)
+	if errors.Is(err, database.ErrOAuthStateNotFound) {
+		http.Error(w, "Login attempt expired, please sign in again", http.StatusBadRequest)
+		return nil, false
+	}
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to load oauth state", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	state := r.URL.Query().Get("state")
+	if loginState.Provider != provider || subtle.ConstantTimeCompare([]byte(state), []byte(loginState.State)) != 1 {
+		http.Error(w, "Invalid state", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// The user denied access or the provider failed
+	if r.URL.Query().Get("error") != "" {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+
+	return loginState, true
+}
+
+// finishLogin links the identity to the signed-in user when the login was started
+// from account settings, otherwise it signs in the identity's user
+func (h *AuthHandler) finishLogin(w http.ResponseWriter, r *http.Request, loginState *database.OAuthState, provider models.Provider, providerID, email, name string) {
+	if loginState.LinkUserID != "" {
+		// The session must still belong to the user who asked to link
+		userID, ok := middleware.GetUserIDFromContext(r.Context())
+		if !ok || userID.String() != loginState.LinkUserID {
+			http.Error(w, "Your session changed while linking, please try again", http.StatusBadRequest)
+			return
+		}
+
+		err := h.userService.LinkIdentity(r.Context(), userID, providerID, provider, email)
+		if errors.Is(err, services.ErrIdentityInUse) {
+			http.Redirect(w, r, "/account?error=identity_in_use", http.StatusSeeOther)
+			return
+		}
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to link identity", "error", err)
+			http.Error(w, "Failed to link account", http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/account?linked=true", http.StatusSeeOther)
+		return
+	}
+
+	// Find or create user
+	user, err := h.userService.FindOrCreate(r.Context(), providerID, provider, email, name)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to find or create user", "error", err)
+		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+
+	h.completeLogin(w, r, user, loginState.ReturnTo)
+}
+
+// completeLogin starts a session for the user and sends them back to returnTo
+func (h *AuthHandler) completeLogin(w http.ResponseWriter, r *http.Request, user *models.User, returnTo string) {
+	// Create session
+	sessionID, err := h.sessionStore.GenerateSessionID()
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to generate session ID", "error", err)
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	// Rotate the session ID on login so a pre-existing session can't be fixated
+	if cookie, err := r.Cookie("session"); err == nil {
+		h.sessionStore.Delete(r.Context(), cookie.Value)
+	}
+
+	if err := h.sessionStore.Set(r.Context(), sessionID, user.ID, middleware.ClientIP(r), r.UserAgent()); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to store session", "error", err)
+		http.Error(w, "Failed to store session", http.StatusInternalServerError)
+		return
+	}
+
+	// Set cookie
+	h.authMiddleware.SetSessionCookie(w, sessionID)
+
+	// Redirect back to the page that required auth
+	http.Redirect(w, r, returnTo, http.StatusSeeOther)
+}
+
+// oidcStateProvider is the provider recorded in the OAuth state of an OIDC login
+func oidcStateProvider(name string) string {
+	return models.ProviderOIDC.String() + ":" + name
+}
+
+// safeReturnTo only allows local paths as post-login redirects to avoid open redirects
+func safeReturnTo(returnTo string) string {
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		return "/movies"
+	}
+	u, err := url.Parse(returnTo)
+	if err != nil || u.Scheme != "" || u.Host != "" || strings.HasPrefix(u.Path, "/auth/") || u.Path == "/login" {
+		return "/movies"
+	}
+	return returnTo
+}
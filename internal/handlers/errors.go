@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/liamwears/reelscore/internal/apperror"
+)
+
+// writeError writes a service error as JSON. Application errors, like TMDB being
+// unavailable or a duplicate, keep their code and message, anything else is reported
+// as an internal error with message. Server errors are logged.
+func writeError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, err error, message string) {
+	appErr := apperror.From(err)
+	if appErr.Status() >= http.StatusInternalServerError {
+		logger.ErrorContext(r.Context(), message, "error", err)
+	}
+	if appErr.Code == apperror.CodeInternal {
+		appErr = apperror.Internal(message)
+	}
+	apperror.Write(w, r, appErr)
+}
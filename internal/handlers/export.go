@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/liamwears/reelscore/internal/apperror"
+	"github.com/liamwears/reelscore/internal/middleware"
+	"github.com/liamwears/reelscore/internal/services"
+)
+
+// exportTimeout is how long streaming an export may take
+const exportTimeout = 10 * time.Minute
+
+// ExportHandler handles library export requests
+type ExportHandler struct {
+	exportService *services.ExportService
+	logger        *slog.Logger
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(exportService *services.ExportService, logger *slog.Logger) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+		logger:        logger,
+	}
+}
+
+// Export handles GET /api/export?format=csv|json|letterboxd
+func (h *ExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Parse format, defaulting to JSON
+	format := services.ExportFormat(r.URL.Query().Get("format"))
+	if format == "" {
+		format = services.ExportFormatJSON
+	}
+	if !format.IsValid() {
+		apperror.Write(w, r, apperror.BadRequest("Format must be csv, json or letterboxd"))
+		return
+	}
+
+	// Large libraries can outlast the server write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportTimeout))
+
+	filename := fmt.Sprintf("reelscore-%s-%s.%s", format, time.Now().Format("2006-01-02"), format.Extension())
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	// Rows are streamed, so an error part way through can only be logged
+	if err := h.exportService.Export(r.Context(), userID, format, w); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to export library", "error", err)
+	}
+}
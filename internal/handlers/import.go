@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/liamwears/reelscore/internal/apperror"
+	"github.com/liamwears/reelscore/internal/middleware"
+	"github.com/liamwears/reelscore/internal/services"
+)
+
+const (
+	// maxImportSize caps the size of uploaded import files
+	maxImportSize = 32 << 20
+	// importTimeout is how long an import request may take to respond
+	importTimeout = 10 * time.Minute
+)
+
+// ImportHandler handles library import requests
+type ImportHandler struct {
+	importService *services.ImportService
+	logger        *slog.Logger
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(importService *services.ImportService, logger *slog.Logger) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+		logger:        logger,
+	}
+}
+
+// Letterboxd handles POST /api/import/letterboxd
+func (h *ImportHandler) Letterboxd(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Read uploaded file
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("A Letterboxd export zip or CSV file is required"))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Failed to read uploaded file"))
+		return
+	}
+
+	// Resolving every film against TMDB can outlast the server write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(importTimeout))
+
+	// Call service
+	report, err := h.importService.ImportLetterboxd(r.Context(), userID, header.Filename, data)
+	if err != nil {
+		if errors.Is(err, services.ErrUnsupportedImport) {
+			apperror.Write(w, r, apperror.BadRequest("Unsupported file, upload the Letterboxd export zip or one of its CSV files"))
+			return
+		}
+		if errors.Is(err, services.ErrImportTooLarge) {
+			apperror.Write(w, r, apperror.BadRequest("The file is too large to be a Letterboxd export"))
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to import Letterboxd export", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to import Letterboxd export"))
+		return
+	}
+
+	// Return import report
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
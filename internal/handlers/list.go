@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"strconv"
+
+	"github.com/liamwears/reelscore/internal/apperror"
+	"github.com/liamwears/reelscore/internal/models"
+)
+
+// parseListOptions reads the library sort and filter options from a query string.
+// Empty values are ignored so unfilled form fields can be submitted as they are.
+// Invalid values are reported as a validation error naming the field.
+func parseListOptions(query url.Values) (models.ListOptions, error) {
+	opts := models.ListOptions{
+		Sort:    models.SortKey(query.Get("sort")),
+		Order:   models.SortOrder(query.Get("order")),
+		Unrated: query.Get("unrated") == "true" || query.Get("unrated") == "on",
+	}
+
+	if opts.Sort != "" && !opts.Sort.IsValid() {
+		return opts, invalidListOption("sort", fmt.Sprintf("invalid sort %q", opts.Sort))
+	}
+	if opts.Order != "" && !opts.Order.IsValid() {
+		return opts, invalidListOption("order", fmt.Sprintf("invalid order %q", opts.Order))
+	}
+
+	for _, f := range []struct {
+		name string
+		dst  **float64
+	}{{"minScore", &opts.MinScore}, {"maxScore", &opts.MaxScore}} {
+		value := query.Get(f.name)
+		if value == "" {
+			continue
+		}
+		score, err := strconv.ParseFloat(value, 64)
+		if err != nil || score < 0 || score > 10 {
+			return opts, invalidListOption(f.name, f.name+" must be a number between 0 and 10")
+		}
+		*f.dst = &score
+	}
+
+	for _, f := range []struct {
+		name string
+		dst  **int
+	}{{"minYear", &opts.MinYear}, {"maxYear", &opts.MaxYear}} {
+		value := query.Get(f.name)
+		if value == "" {
+			continue
+		}
+		year, err := strconv.Atoi(value)
+		if err != nil || year < 1800 || year > 3000 {
+			return opts, invalidListOption(f.name, f.name+" must be a valid year")
+		}
+		*f.dst = &year
+	}
+
+	return opts, nil
+}
+
+// invalidListOption creates the validation error for one list option
+func invalidListOption(field, message string) error {
+	return apperror.Validation(message, map[string]string{field: message})
+}
+
+// filterQuery re-encodes the listing query string without the page so pagination
+// links keep the active search, sort and filters
+func filterQuery(query url.Values) template.URL {
+	values := url.Values{}
+	for key, v := range query {
+		if key != "page" && len(v) > 0 && v[0] != "" {
+			values[key] = v
+		}
+	}
+	return template.URL(values.Encode())
+}
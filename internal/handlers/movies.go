@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/liamwears/reelscore/internal/apperror"
 	"github.com/liamwears/reelscore/internal/middleware"
 	"github.com/liamwears/reelscore/internal/models"
 	"github.com/liamwears/reelscore/internal/services"
@@ -16,11 +18,11 @@ import (
 // MovieHandler handles movie-related requests
 type MovieHandler struct {
 	movieService *services.MovieService
-	logger       *log.Logger
+	logger       *slog.Logger
 }
 
 // NewMovieHandler creates a new movie handler
-func NewMovieHandler(movieService *services.MovieService, logger *log.Logger) *MovieHandler {
+func NewMovieHandler(movieService *services.MovieService, logger *slog.Logger) *MovieHandler {
 	return &MovieHandler{
 		movieService: movieService,
 		logger:       logger,
@@ -32,7 +34,7 @@ func (h *MovieHandler) List(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 		return
 	}
 
@@ -52,16 +54,23 @@ func (h *MovieHandler) List(w http.ResponseWriter, r *http.Request) {
 		limit = 27
 	}
 
+	listOptions, err := parseListOptions(query)
+	if err != nil {
+		apperror.Write(w, r, err)
+		return
+	}
+
 	// Call service
 	result, err := h.movieService.List(r.Context(), userID, models.ListMoviesInput{
-		Watched: watched,
-		Query:   searchQuery,
-		Page:    page,
-		Limit:   limit,
+		ListOptions: listOptions,
+		Watched:     watched,
+		Query:       searchQuery,
+		Page:        page,
+		Limit:       limit,
 	})
 	if err != nil {
-		h.logger.Printf("Failed to list movies: %v", err)
-		http.Error(w, `{"error":"Failed to fetch movies"}`, http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Failed to list movies", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to fetch movies"))
 		return
 	}
 
@@ -75,29 +84,29 @@ func (h *MovieHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 		return
 	}
 
 	// Parse request body
 	var input models.CreateMovieInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		h.logger.Printf("Failed to decode request body: %v", err)
-		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		h.logger.ErrorContext(r.Context(), "Failed to decode request body", "error", err)
+		apperror.Write(w, r, apperror.BadRequest("Invalid request body"))
 		return
 	}
-	h.logger.Printf("Received movie input: %+v", input)
+	h.logger.DebugContext(r.Context(), "Received movie input", "input", input)
 
 	// Call service
 	movie, err := h.movieService.Create(r.Context(), userID, input)
 	if err != nil {
-		h.logger.Printf("Failed to create movie: %v", err)
-		// Check for duplicate
-		if err.Error() == "duplicate key value violates unique constraint" {
-			http.Error(w, `{"error":"Movie already in your library"}`, http.StatusConflict)
+		// The library holds each TMDB title once
+		if errors.Is(err, apperror.ErrConflict) {
+			apperror.Write(w, r, apperror.Conflict("Movie already in your library"))
 			return
 		}
-		http.Error(w, `{"error":"Failed to create movie"}`, http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Failed to create movie", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to create movie"))
 		return
 	}
 
@@ -115,7 +124,7 @@ func (h *MovieHandler) Get(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 		return
 	}
 
@@ -123,19 +132,19 @@ func (h *MovieHandler) Get(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	movieID, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid movie ID"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Invalid movie ID"))
 		return
 	}
 
 	// Call service
 	movie, err := h.movieService.Get(r.Context(), movieID, userID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			http.Error(w, `{"error":"Movie not found"}`, http.StatusNotFound)
+		if errors.Is(err, pgx.ErrNoRows) {
+			apperror.Write(w, r, apperror.NotFound("Movie not found"))
 			return
 		}
-		h.logger.Printf("Failed to get movie: %v", err)
-		http.Error(w, `{"error":"Failed to fetch movie"}`, http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Failed to get movie", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to fetch movie"))
 		return
 	}
 
@@ -149,7 +158,7 @@ func (h *MovieHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 		return
 	}
 
@@ -157,14 +166,14 @@ func (h *MovieHandler) Update(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	movieID, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid movie ID"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Invalid movie ID"))
 		return
 	}
 
 	// Parse request body
 	var input models.UpdateMovieInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Invalid request body"))
 		return
 	}
 	input.ID = movieID
@@ -172,12 +181,12 @@ func (h *MovieHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// Call service
 	movie, err := h.movieService.Update(r.Context(), userID, input)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			http.Error(w, `{"error":"Movie not found"}`, http.StatusNotFound)
+		if errors.Is(err, pgx.ErrNoRows) {
+			apperror.Write(w, r, apperror.NotFound("Movie not found"))
 			return
 		}
-		h.logger.Printf("Failed to update movie: %v", err)
-		http.Error(w, `{"error":"Failed to update movie"}`, http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Failed to update movie", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to update movie"))
 		return
 	}
 
@@ -194,7 +203,7 @@ func (h *MovieHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 		return
 	}
 
@@ -202,19 +211,19 @@ func (h *MovieHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	movieID, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid movie ID"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Invalid movie ID"))
 		return
 	}
 
 	// Call service
 	err = h.movieService.Delete(r.Context(), movieID, userID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			http.Error(w, `{"error":"Movie not found"}`, http.StatusNotFound)
+		if errors.Is(err, pgx.ErrNoRows) {
+			apperror.Write(w, r, apperror.NotFound("Movie not found"))
 			return
 		}
-		h.logger.Printf("Failed to delete movie: %v", err)
-		http.Error(w, `{"error":"Failed to delete movie"}`, http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Failed to delete movie", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to delete movie"))
 		return
 	}
 
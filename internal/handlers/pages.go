@@ -1,9 +1,10 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/liamwears/reelscore/internal/middleware"
 	"github.com/liamwears/reelscore/internal/models"
@@ -12,21 +13,23 @@ import (
 
 // PageHandler handles page rendering
 type PageHandler struct {
-	tmdbService  *services.TMDBService
-	movieService *services.MovieService
-	serieService *services.SerieService
-	renderer     *Renderer
-	logger       *log.Logger
+	tmdbService    *services.TMDBService
+	movieService   *services.MovieService
+	serieService   *services.SerieService
+	viewingService *services.ViewingService
+	renderer       *Renderer
+	logger         *slog.Logger
 }
 
 // NewPageHandler creates a new page handler
-func NewPageHandler(tmdbService *services.TMDBService, movieService *services.MovieService, serieService *services.SerieService, renderer *Renderer, logger *log.Logger) *PageHandler {
+func NewPageHandler(tmdbService *services.TMDBService, movieService *services.MovieService, serieService *services.SerieService, viewingService *services.ViewingService, renderer *Renderer, logger *slog.Logger) *PageHandler {
 	return &PageHandler{
-		tmdbService:  tmdbService,
-		movieService: movieService,
-		serieService: serieService,
-		renderer:     renderer,
-		logger:       logger,
+		tmdbService:    tmdbService,
+		movieService:   movieService,
+		serieService:   serieService,
+		viewingService: viewingService,
+		renderer:       renderer,
+		logger:         logger,
 	}
 }
 
@@ -54,8 +57,8 @@ func (h *PageHandler) BrowseMovies(w http.ResponseWriter, r *http.Request) {
 		// Search movies
 		result, err := h.tmdbService.SearchMovies(r.Context(), query, page)
 		if err != nil {
-			h.logger.Printf("Failed to search movies: %v", err)
-			http.Error(w, "Failed to search movies", http.StatusInternalServerError)
+			h.logger.ErrorContext(r.Context(), "Failed to search movies", "error", err)
+			http.Error(w, "Failed to search movies", tmdbErrorStatus(err))
 			return
 		}
 		movies = result.Results
@@ -64,8 +67,8 @@ func (h *PageHandler) BrowseMovies(w http.ResponseWriter, r *http.Request) {
 		// Discover popular movies
 		result, err := h.tmdbService.DiscoverMovies(r.Context(), page)
 		if err != nil {
-			h.logger.Printf("Failed to discover movies: %v", err)
-			http.Error(w, "Failed to discover movies", http.StatusInternalServerError)
+			h.logger.ErrorContext(r.Context(), "Failed to discover movies", "error", err)
+			http.Error(w, "Failed to discover movies", tmdbErrorStatus(err))
 			return
 		}
 		movies = result.Results
@@ -82,7 +85,7 @@ func (h *PageHandler) BrowseMovies(w http.ResponseWriter, r *http.Request) {
 		"TotalPages": totalPages,
 	}
 
-	h.renderer.RenderPage(w, "browse-movies.html", data)
+	h.renderer.RenderPage(w, r, "browse-movies.html", data)
 }
 
 // BrowseSeries handles GET /series
@@ -109,8 +112,8 @@ func (h *PageHandler) BrowseSeries(w http.ResponseWriter, r *http.Request) {
 		// Search TV series
 		result, err := h.tmdbService.SearchTV(r.Context(), query, page)
 		if err != nil {
-			h.logger.Printf("Failed to search TV series: %v", err)
-			http.Error(w, "Failed to search TV series", http.StatusInternalServerError)
+			h.logger.ErrorContext(r.Context(), "Failed to search TV series", "error", err)
+			http.Error(w, "Failed to search TV series", tmdbErrorStatus(err))
 			return
 		}
 		series = result.Results
@@ -119,8 +122,8 @@ func (h *PageHandler) BrowseSeries(w http.ResponseWriter, r *http.Request) {
 		// Discover popular TV series
 		result, err := h.tmdbService.DiscoverTV(r.Context(), page)
 		if err != nil {
-			h.logger.Printf("Failed to discover TV series: %v", err)
-			http.Error(w, "Failed to discover TV series", http.StatusInternalServerError)
+			h.logger.ErrorContext(r.Context(), "Failed to discover TV series", "error", err)
+			http.Error(w, "Failed to discover TV series", tmdbErrorStatus(err))
 			return
 		}
 		series = result.Results
@@ -137,7 +140,7 @@ func (h *PageHandler) BrowseSeries(w http.ResponseWriter, r *http.Request) {
 		"TotalPages": totalPages,
 	}
 
-	h.renderer.RenderPage(w, "browse-series.html", data)
+	h.renderer.RenderPage(w, r, "browse-series.html", data)
 }
 
 // LibraryMovies handles GET /library/movies/watched and /library/movies/watchlist
@@ -159,32 +162,40 @@ func (h *PageHandler) LibraryMovies(w http.ResponseWriter, r *http.Request) {
 	if page < 1 {
 		page = 1
 	}
+	listOptions, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Fetch movies from database
 	result, err := h.movieService.List(r.Context(), userID, models.ListMoviesInput{
-		Watched: watched,
-		Query:   query,
-		Page:    page,
-		Limit:   27,
+		ListOptions: listOptions,
+		Watched:     watched,
+		Query:       query,
+		Page:        page,
+		Limit:       27,
 	})
 	if err != nil {
-		h.logger.Printf("Failed to list library movies: %v", err)
+		h.logger.ErrorContext(r.Context(), "Failed to list library movies", "error", err)
 		http.Error(w, "Failed to fetch movies", http.StatusInternalServerError)
 		return
 	}
 
 	// Render template
 	data := map[string]interface{}{
-		"User":       user,
-		"ActivePage": "library-movies",
-		"Movies":     result.Results,
-		"Watched":    watched,
-		"Query":      query,
-		"Page":       page,
-		"TotalPages": result.TotalPages,
+		"User":        user,
+		"ActivePage":  "library-movies",
+		"Movies":      result.Results,
+		"Watched":     watched,
+		"Query":       query,
+		"Options":     listOptions,
+		"FilterQuery": filterQuery(r.URL.Query()),
+		"Page":        page,
+		"TotalPages":  result.TotalPages,
 	}
 
-	h.renderer.RenderPage(w, "library-movies.html", data)
+	h.renderer.RenderPage(w, r, "library-movies.html", data)
 }
 
 // LibrarySeries handles GET /library/series/watched and /library/series/watchlist
@@ -206,32 +217,83 @@ func (h *PageHandler) LibrarySeries(w http.ResponseWriter, r *http.Request) {
 	if page < 1 {
 		page = 1
 	}
+	listOptions, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Fetch series from database
 	result, err := h.serieService.List(r.Context(), userID, models.ListSeriesInput{
-		Watched: watched,
-		Query:   query,
-		Page:    page,
-		Limit:   27,
+		ListOptions: listOptions,
+		Watched:     watched,
+		Query:       query,
+		Page:        page,
+		Limit:       27,
 	})
 	if err != nil {
-		h.logger.Printf("Failed to list library series: %v", err)
+		h.logger.ErrorContext(r.Context(), "Failed to list library series", "error", err)
 		http.Error(w, "Failed to fetch series", http.StatusInternalServerError)
 		return
 	}
 
+	// Render template
+	data := map[string]interface{}{
+		"User":        user,
+		"ActivePage":  "library-series",
+		"Series":      result.Results,
+		"Watched":     watched,
+		"Query":       query,
+		"Options":     listOptions,
+		"FilterQuery": filterQuery(r.URL.Query()),
+		"Page":        page,
+		"TotalPages":  result.TotalPages,
+	}
+
+	h.renderer.RenderPage(w, r, "library-series.html", data)
+}
+
+// Diary handles GET /diary?month=YYYY-MM
+func (h *PageHandler) Diary(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+
+	// Parse month, defaulting to the current one
+	now := time.Now()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if m := r.URL.Query().Get("month"); m != "" {
+		parsed, err := time.Parse("2006-01", m)
+		if err != nil {
+			http.Error(w, "Invalid month, expected YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		month = parsed
+	}
+
+	// Fetch viewings from database
+	entries, err := h.viewingService.Diary(r.Context(), userID, month)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to fetch diary", "error", err)
+		http.Error(w, "Failed to fetch diary", http.StatusInternalServerError)
+		return
+	}
+
 	// Render template
 	data := map[string]interface{}{
 		"User":       user,
-		"ActivePage": "library-series",
-		"Series":     result.Results,
-		"Watched":    watched,
-		"Query":      query,
-		"Page":       page,
-		"TotalPages": result.TotalPages,
+		"ActivePage": "diary",
+		"Entries":    entries,
+		"Month":      month,
+		"PrevMonth":  month.AddDate(0, -1, 0).Format("2006-01"),
+		"NextMonth":  month.AddDate(0, 1, 0).Format("2006-01"),
 	}
 
-	h.renderer.RenderPage(w, "library-series.html", data)
+	h.renderer.RenderPage(w, r, "diary.html", data)
 }
 
 // Search handles GET /search
@@ -254,7 +316,7 @@ func (h *PageHandler) Search(w http.ResponseWriter, r *http.Request) {
 		// Search movies
 		movieResult, err := h.tmdbService.SearchMovies(r.Context(), query, 1)
 		if err != nil {
-			h.logger.Printf("Failed to search movies: %v", err)
+			h.logger.ErrorContext(r.Context(), "Failed to search movies", "error", err)
 		} else {
 			movies = movieResult.Results
 			// Limit to top 10 results
@@ -266,7 +328,7 @@ func (h *PageHandler) Search(w http.ResponseWriter, r *http.Request) {
 		// Search TV series
 		seriesResult, err := h.tmdbService.SearchTV(r.Context(), query, 1)
 		if err != nil {
-			h.logger.Printf("Failed to search TV series: %v", err)
+			h.logger.ErrorContext(r.Context(), "Failed to search TV series", "error", err)
 		} else {
 			series = seriesResult.Results
 			// Limit to top 10 results
@@ -285,5 +347,5 @@ func (h *PageHandler) Search(w http.ResponseWriter, r *http.Request) {
 		"Series":     series,
 	}
 
-	h.renderer.RenderPage(w, "search.html", data)
+	h.renderer.RenderPage(w, r, "search.html", data)
 }
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/liamwears/reelscore/internal/apperror"
+	"github.com/liamwears/reelscore/internal/middleware"
+	"github.com/liamwears/reelscore/internal/models"
+	"github.com/liamwears/reelscore/internal/services"
+)
+
+// ProgressHandler handles episode progress requests
+type ProgressHandler struct {
+	progressService *services.ProgressService
+	logger          *slog.Logger
+}
+
+// NewProgressHandler creates a new progress handler
+func NewProgressHandler(progressService *services.ProgressService, logger *slog.Logger) *ProgressHandler {
+	return &ProgressHandler{
+		progressService: progressService,
+		logger:          logger,
+	}
+}
+
+// Get handles GET /api/series/{id}/progress
+func (h *ProgressHandler) Get(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Get serie ID from path
+	serieID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid serie ID"))
+		return
+	}
+
+	// Call service
+	progress, err := h.progressService.Get(r.Context(), serieID, userID)
+	if err != nil {
+		h.writeError(w, r, err, "Failed to fetch progress")
+		return
+	}
+
+	// Return progress
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
+
+// Mark handles POST /api/series/{id}/progress
+func (h *ProgressHandler) Mark(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Get serie ID from path
+	serieID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid serie ID"))
+		return
+	}
+
+	// Parse request body
+	var input models.MarkProgressInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid request body"))
+		return
+	}
+	if input.Scope == "" {
+		input.Scope = models.ProgressScopeEpisode
+	}
+	if input.Season < 1 || (input.Scope != models.ProgressScopeSeason && input.Episode < 1) {
+		fields := map[string]string{}
+		if input.Season < 1 {
+			fields["season"] = "Season is required"
+		}
+		if input.Scope != models.ProgressScopeSeason && input.Episode < 1 {
+			fields["episode"] = "Episode is required"
+		}
+		apperror.Write(w, r, apperror.Validation("Season and episode are required", fields))
+		return
+	}
+
+	// Call service
+	progress, err := h.progressService.Mark(r.Context(), serieID, userID, input)
+	if err != nil {
+		h.writeError(w, r, err, "Failed to update progress")
+		return
+	}
+
+	// Return updated progress
+	if progress.MarkedWatched {
+		w.Header().Set("HX-Trigger", `{"showMessage":"Serie marked as watched"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
+
+// Unmark handles DELETE /api/series/{id}/progress?season=N[&episode=E]
+func (h *ProgressHandler) Unmark(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Get serie ID from path
+	serieID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid serie ID"))
+		return
+	}
+
+	// Parse query parameters, omitting episode unmarks the whole season
+	season, err := strconv.Atoi(r.URL.Query().Get("season"))
+	if err != nil || season < 1 {
+		apperror.Write(w, r, apperror.BadRequest("Invalid season number"))
+		return
+	}
+	episode, _ := strconv.Atoi(r.URL.Query().Get("episode"))
+
+	// Call service
+	progress, err := h.progressService.Unmark(r.Context(), serieID, userID, season, episode)
+	if err != nil {
+		h.writeError(w, r, err, "Failed to update progress")
+		return
+	}
+
+	// Return updated progress
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
+
+// writeError maps progress service errors to HTTP responses
+func (h *ProgressHandler) writeError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		apperror.Write(w, r, apperror.NotFound("Serie not found"))
+	case errors.Is(err, services.ErrEpisodeNotAired):
+		apperror.Write(w, r, apperror.Validation("Episode has not aired", nil))
+	case errors.Is(err, services.ErrInvalidProgressScope):
+		apperror.Write(w, r, apperror.Validation("Invalid progress scope", map[string]string{"scope": "Invalid progress scope"}))
+	default:
+		writeError(w, r, h.logger, err, message)
+	}
+}
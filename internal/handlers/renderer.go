@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"html/template"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+
+	"github.com/liamwears/reelscore/internal/middleware"
 )
 
 //go:embed templates/*
@@ -15,11 +17,11 @@ var templatesFS embed.FS
 // Renderer handles template rendering
 type Renderer struct {
 	templates *template.Template
-	logger    *log.Logger
+	logger    *slog.Logger
 }
 
 // NewRenderer creates a new template renderer
-func NewRenderer(logger *log.Logger) (*Renderer, error) {
+func NewRenderer(logger *slog.Logger) (*Renderer, error) {
 	// Create template with custom functions
 	funcMap := template.FuncMap{
 		"add": func(a, b int) int { return a + b },
@@ -73,12 +75,23 @@ func (r *Renderer) Render(w io.Writer, name string, data interface{}) error {
 	return tmpl.ExecuteTemplate(w, name, data)
 }
 
-// RenderPage renders a page template and handles errors
-func (r *Renderer) RenderPage(w http.ResponseWriter, name string, data interface{}) {
+// RenderPage renders a page template and handles errors. Map data gets the session's
+// CSRF token as CSRFToken, which the layout hands to HTMX, and the request's
+// Content-Security-Policy nonce as CSPNonce for inline scripts.
+func (r *Renderer) RenderPage(w http.ResponseWriter, req *http.Request, name string, data interface{}) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
+	if m, ok := data.(map[string]interface{}); ok {
+		if token, ok := middleware.GetCSRFTokenFromContext(req.Context()); ok {
+			m["CSRFToken"] = token
+		}
+		if nonce, ok := middleware.GetCSPNonceFromContext(req.Context()); ok {
+			m["CSPNonce"] = nonce
+		}
+	}
+
 	if err := r.Render(w, name, data); err != nil {
-		r.logger.Printf("Failed to render template %s: %v", name, err)
+		r.logger.ErrorContext(req.Context(), "Failed to render template", "template", name, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
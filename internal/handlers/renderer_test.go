@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/liamwears/reelscore/internal/middleware"
+)
+
+func TestRenderPageUsesCSPNonce(t *testing.T) {
+	renderer, err := NewRenderer(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := middleware.SecurityHeaders(middleware.SecurityHeadersConfig{
+		ContentSecurityPolicy: middleware.DefaultContentSecurityPolicy,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		renderer.RenderPage(w, r, "search.html", map[string]interface{}{})
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search", nil))
+
+	match := regexp.MustCompile(`'nonce-([^']+)'`).FindStringSubmatch(w.Header().Get("Content-Security-Policy"))
+	if match == nil {
+		t.Fatalf("Content-Security-Policy %q has no nonce", w.Header().Get("Content-Security-Policy"))
+	}
+	body := w.Body.String()
+	inline := strings.Count(body, "<script>") + strings.Count(body, "<script ")
+	external := strings.Count(body, "<script src=")
+	if withNonce := strings.Count(body, `<script nonce="`+match[1]+`">`); withNonce == 0 || withNonce != inline-external {
+		t.Errorf("%d of %d inline scripts carry the nonce %q", withNonce, inline-external, match[1])
+	}
+}
@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/liamwears/reelscore/internal/apperror"
 	"github.com/liamwears/reelscore/internal/middleware"
 	"github.com/liamwears/reelscore/internal/models"
 	"github.com/liamwears/reelscore/internal/services"
@@ -16,11 +18,11 @@ import (
 // SerieHandler handles series-related requests
 type SerieHandler struct {
 	serieService *services.SerieService
-	logger       *log.Logger
+	logger       *slog.Logger
 }
 
 // NewSerieHandler creates a new serie handler
-func NewSerieHandler(serieService *services.SerieService, logger *log.Logger) *SerieHandler {
+func NewSerieHandler(serieService *services.SerieService, logger *slog.Logger) *SerieHandler {
 	return &SerieHandler{
 		serieService: serieService,
 		logger:       logger,
@@ -32,7 +34,7 @@ func (h *SerieHandler) List(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 		return
 	}
 
@@ -52,16 +54,23 @@ func (h *SerieHandler) List(w http.ResponseWriter, r *http.Request) {
 		limit = 27
 	}
 
+	listOptions, err := parseListOptions(query)
+	if err != nil {
+		apperror.Write(w, r, err)
+		return
+	}
+
 	// Call service
 	result, err := h.serieService.List(r.Context(), userID, models.ListSeriesInput{
-		Watched: watched,
-		Query:   searchQuery,
-		Page:    page,
-		Limit:   limit,
+		ListOptions: listOptions,
+		Watched:     watched,
+		Query:       searchQuery,
+		Page:        page,
+		Limit:       limit,
 	})
 	if err != nil {
-		h.logger.Printf("Failed to list series: %v", err)
-		http.Error(w, `{"error":"Failed to fetch series"}`, http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Failed to list series", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to fetch series"))
 		return
 	}
 
@@ -75,27 +84,27 @@ func (h *SerieHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 		return
 	}
 
 	// Parse request body
 	var input models.CreateSerieInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Invalid request body"))
 		return
 	}
 
 	// Call service
 	serie, err := h.serieService.Create(r.Context(), userID, input)
 	if err != nil {
-		h.logger.Printf("Failed to create serie: %v", err)
-		// Check for duplicate
-		if err.Error() == "duplicate key value violates unique constraint" {
-			http.Error(w, `{"error":"Serie already in your library"}`, http.StatusConflict)
+		// The library holds each TMDB title once
+		if errors.Is(err, apperror.ErrConflict) {
+			apperror.Write(w, r, apperror.Conflict("Serie already in your library"))
 			return
 		}
-		http.Error(w, `{"error":"Failed to create serie"}`, http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Failed to create serie", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to create serie"))
 		return
 	}
 
@@ -113,7 +122,7 @@ func (h *SerieHandler) Get(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 		return
 	}
 
@@ -121,19 +130,19 @@ func (h *SerieHandler) Get(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	serieID, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid serie ID"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Invalid serie ID"))
 		return
 	}
 
 	// Call service
 	serie, err := h.serieService.Get(r.Context(), serieID, userID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			http.Error(w, `{"error":"Serie not found"}`, http.StatusNotFound)
+		if errors.Is(err, pgx.ErrNoRows) {
+			apperror.Write(w, r, apperror.NotFound("Serie not found"))
 			return
 		}
-		h.logger.Printf("Failed to get serie: %v", err)
-		http.Error(w, `{"error":"Failed to fetch serie"}`, http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Failed to get serie", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to fetch serie"))
 		return
 	}
 
@@ -147,7 +156,7 @@ func (h *SerieHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 		return
 	}
 
@@ -155,14 +164,14 @@ func (h *SerieHandler) Update(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	serieID, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid serie ID"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Invalid serie ID"))
 		return
 	}
 
 	// Parse request body
 	var input models.UpdateSerieInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Invalid request body"))
 		return
 	}
 	input.ID = serieID
@@ -170,12 +179,12 @@ func (h *SerieHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// Call service
 	serie, err := h.serieService.Update(r.Context(), userID, input)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			http.Error(w, `{"error":"Serie not found"}`, http.StatusNotFound)
+		if errors.Is(err, pgx.ErrNoRows) {
+			apperror.Write(w, r, apperror.NotFound("Serie not found"))
 			return
 		}
-		h.logger.Printf("Failed to update serie: %v", err)
-		http.Error(w, `{"error":"Failed to update serie"}`, http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Failed to update serie", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to update serie"))
 		return
 	}
 
@@ -192,7 +201,7 @@ func (h *SerieHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	// Get user from context
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 		return
 	}
 
@@ -200,19 +209,19 @@ func (h *SerieHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	serieID, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid serie ID"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Invalid serie ID"))
 		return
 	}
 
 	// Call service
 	err = h.serieService.Delete(r.Context(), serieID, userID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			http.Error(w, `{"error":"Serie not found"}`, http.StatusNotFound)
+		if errors.Is(err, pgx.ErrNoRows) {
+			apperror.Write(w, r, apperror.NotFound("Serie not found"))
 			return
 		}
-		h.logger.Printf("Failed to delete serie: %v", err)
-		http.Error(w, `{"error":"Failed to delete serie"}`, http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "Failed to delete serie", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to delete serie"))
 		return
 	}
 
@@ -2,21 +2,22 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 
+	"github.com/liamwears/reelscore/internal/apperror"
 	"github.com/liamwears/reelscore/internal/services"
 )
 
 // TMDBHandler handles TMDB API requests
 type TMDBHandler struct {
 	tmdbService *services.TMDBService
-	logger      *log.Logger
+	logger      *slog.Logger
 }
 
 // NewTMDBHandler creates a new TMDB handler
-func NewTMDBHandler(tmdbService *services.TMDBService, logger *log.Logger) *TMDBHandler {
+func NewTMDBHandler(tmdbService *services.TMDBService, logger *slog.Logger) *TMDBHandler {
 	return &TMDBHandler{
 		tmdbService: tmdbService,
 		logger:      logger,
@@ -29,15 +30,14 @@ func (h *TMDBHandler) GetMovie(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	movieID, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid movie ID"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Invalid movie ID"))
 		return
 	}
 
 	// Call TMDB service
 	movie, err := h.tmdbService.GetMovie(r.Context(), movieID)
 	if err != nil {
-		h.logger.Printf("Failed to fetch movie from TMDB: %v", err)
-		http.Error(w, `{"error":"Failed to fetch movie"}`, http.StatusInternalServerError)
+		writeError(w, r, h.logger, err, "Failed to fetch movie")
 		return
 	}
 
@@ -46,21 +46,42 @@ func (h *TMDBHandler) GetMovie(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(movie)
 }
 
+// GetMovieDetails handles GET /api/tmdb/movie/{id}/details
+func (h *TMDBHandler) GetMovieDetails(w http.ResponseWriter, r *http.Request) {
+	// Get movie ID from path
+	idStr := r.PathValue("id")
+	movieID, err := strconv.Atoi(idStr)
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid movie ID"))
+		return
+	}
+
+	// Call TMDB service
+	details, err := h.tmdbService.GetMovieDetails(r.Context(), movieID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to fetch movie details")
+		return
+	}
+
+	// Return movie details
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
 // GetTV handles GET /api/tmdb/tv/{id}
 func (h *TMDBHandler) GetTV(w http.ResponseWriter, r *http.Request) {
 	// Get TV ID from path
 	idStr := r.PathValue("id")
 	tvID, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, `{"error":"Invalid TV ID"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Invalid TV ID"))
 		return
 	}
 
 	// Call TMDB service
 	tv, err := h.tmdbService.GetTV(r.Context(), tvID)
 	if err != nil {
-		h.logger.Printf("Failed to fetch TV from TMDB: %v", err)
-		http.Error(w, `{"error":"Failed to fetch TV series"}`, http.StatusInternalServerError)
+		writeError(w, r, h.logger, err, "Failed to fetch TV series")
 		return
 	}
 
@@ -69,11 +90,89 @@ func (h *TMDBHandler) GetTV(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tv)
 }
 
+// GetTVDetails handles GET /api/tmdb/tv/{id}/details
+func (h *TMDBHandler) GetTVDetails(w http.ResponseWriter, r *http.Request) {
+	// Get TV ID from path
+	tvID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid TV ID"))
+		return
+	}
+
+	// Call TMDB service
+	details, err := h.tmdbService.GetTVDetails(r.Context(), tvID)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to fetch TV details")
+		return
+	}
+
+	// Return TV details
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
+// GetSeason handles GET /api/tmdb/tv/{id}/season/{n}
+func (h *TMDBHandler) GetSeason(w http.ResponseWriter, r *http.Request) {
+	// Get TV ID and season number from path
+	tvID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid TV ID"))
+		return
+	}
+	seasonNumber, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || seasonNumber < 0 {
+		apperror.Write(w, r, apperror.BadRequest("Invalid season number"))
+		return
+	}
+
+	// Call TMDB service
+	season, err := h.tmdbService.GetSeason(r.Context(), tvID, seasonNumber)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to fetch season")
+		return
+	}
+
+	// Return season
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(season)
+}
+
+// GetEpisode handles GET /api/tmdb/tv/{id}/season/{n}/episode/{e}
+func (h *TMDBHandler) GetEpisode(w http.ResponseWriter, r *http.Request) {
+	// Get TV ID, season and episode number from path
+	tvID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid TV ID"))
+		return
+	}
+	seasonNumber, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || seasonNumber < 0 {
+		apperror.Write(w, r, apperror.BadRequest("Invalid season number"))
+		return
+	}
+	episodeNumber, err := strconv.Atoi(r.PathValue("e"))
+	if err != nil || episodeNumber < 1 {
+		apperror.Write(w, r, apperror.BadRequest("Invalid episode number"))
+		return
+	}
+
+	// Call TMDB service
+	episode, err := h.tmdbService.GetEpisode(r.Context(), tvID, seasonNumber, episodeNumber)
+	if err != nil {
+		writeError(w, r, h.logger, err, "Failed to fetch episode")
+		return
+	}
+
+	// Return episode
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(episode)
+}
+
 // SearchMulti handles GET /api/tmdb/search/multi
 func (h *TMDBHandler) SearchMulti(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("query")
 	if query == "" {
-		http.Error(w, `{"error":"Query parameter is required"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Query parameter is required"))
 		return
 	}
 
@@ -85,8 +184,7 @@ func (h *TMDBHandler) SearchMulti(w http.ResponseWriter, r *http.Request) {
 	// Call TMDB service
 	result, err := h.tmdbService.SearchMulti(r.Context(), query, page)
 	if err != nil {
-		h.logger.Printf("Failed to search TMDB: %v", err)
-		http.Error(w, `{"error":"Failed to search"}`, http.StatusInternalServerError)
+		writeError(w, r, h.logger, err, "Failed to search")
 		return
 	}
 
@@ -99,7 +197,7 @@ func (h *TMDBHandler) SearchMulti(w http.ResponseWriter, r *http.Request) {
 func (h *TMDBHandler) SearchMovies(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("query")
 	if query == "" {
-		http.Error(w, `{"error":"Query parameter is required"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Query parameter is required"))
 		return
 	}
 
@@ -111,8 +209,7 @@ func (h *TMDBHandler) SearchMovies(w http.ResponseWriter, r *http.Request) {
 	// Call TMDB service
 	result, err := h.tmdbService.SearchMovies(r.Context(), query, page)
 	if err != nil {
-		h.logger.Printf("Failed to search movies: %v", err)
-		http.Error(w, `{"error":"Failed to search movies"}`, http.StatusInternalServerError)
+		writeError(w, r, h.logger, err, "Failed to search movies")
 		return
 	}
 
@@ -125,7 +222,7 @@ func (h *TMDBHandler) SearchMovies(w http.ResponseWriter, r *http.Request) {
 func (h *TMDBHandler) SearchTV(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("query")
 	if query == "" {
-		http.Error(w, `{"error":"Query parameter is required"}`, http.StatusBadRequest)
+		apperror.Write(w, r, apperror.BadRequest("Query parameter is required"))
 		return
 	}
 
@@ -137,8 +234,7 @@ func (h *TMDBHandler) SearchTV(w http.ResponseWriter, r *http.Request) {
 	// Call TMDB service
 	result, err := h.tmdbService.SearchTV(r.Context(), query, page)
 	if err != nil {
-		h.logger.Printf("Failed to search TV: %v", err)
-		http.Error(w, `{"error":"Failed to search TV series"}`, http.StatusInternalServerError)
+		writeError(w, r, h.logger, err, "Failed to search TV series")
 		return
 	}
 
@@ -157,8 +253,7 @@ func (h *TMDBHandler) DiscoverMovies(w http.ResponseWriter, r *http.Request) {
 	// Call TMDB service
 	result, err := h.tmdbService.DiscoverMovies(r.Context(), page)
 	if err != nil {
-		h.logger.Printf("Failed to discover movies: %v", err)
-		http.Error(w, `{"error":"Failed to discover movies"}`, http.StatusInternalServerError)
+		writeError(w, r, h.logger, err, "Failed to discover movies")
 		return
 	}
 
@@ -177,8 +272,7 @@ func (h *TMDBHandler) DiscoverTV(w http.ResponseWriter, r *http.Request) {
 	// Call TMDB service
 	result, err := h.tmdbService.DiscoverTV(r.Context(), page)
 	if err != nil {
-		h.logger.Printf("Failed to discover TV: %v", err)
-		http.Error(w, `{"error":"Failed to discover TV series"}`, http.StatusInternalServerError)
+		writeError(w, r, h.logger, err, "Failed to discover TV series")
 		return
 	}
 
@@ -186,3 +280,8 @@ func (h *TMDBHandler) DiscoverTV(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
+
+// tmdbErrorStatus maps TMDB service errors to HTTP status codes
+func tmdbErrorStatus(err error) int {
+	return apperror.From(err).Status()
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/liamwears/reelscore/internal/apperror"
+	"github.com/liamwears/reelscore/internal/middleware"
+	"github.com/liamwears/reelscore/internal/models"
+	"github.com/liamwears/reelscore/internal/services"
+)
+
+// APITokenHandler handles personal access token requests
+type APITokenHandler struct {
+	tokenService *services.APITokenService
+	logger       *slog.Logger
+}
+
+// NewAPITokenHandler creates a new API token handler
+func NewAPITokenHandler(tokenService *services.APITokenService, logger *slog.Logger) *APITokenHandler {
+	return &APITokenHandler{
+		tokenService: tokenService,
+		logger:       logger,
+	}
+}
+
+// List handles GET /api/tokens
+func (h *APITokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Call service
+	tokens, err := h.tokenService.List(r.Context(), userID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list api tokens", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to fetch tokens"))
+		return
+	}
+
+	// Return JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Create handles POST /api/tokens. The token secret is only in this response.
+func (h *APITokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Parse request body
+	var input models.CreateAPITokenInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid request body"))
+		return
+	}
+
+	// Call service
+	token, err := h.tokenService.Create(r.Context(), userID, input)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPITokenInput) {
+			apperror.Write(w, r, apperror.Validation(err.Error(), nil))
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to create api token", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to create token"))
+		return
+	}
+
+	// Return created token
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+// Revoke handles DELETE /api/tokens/{id}
+func (h *APITokenHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Get token ID from path
+	tokenID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid token ID"))
+		return
+	}
+
+	// Call service
+	err = h.tokenService.Revoke(r.Context(), userID, tokenID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			apperror.Write(w, r, apperror.NotFound("Token not found"))
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to revoke api token", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to revoke token"))
+		return
+	}
+
+	// Return success
+	w.Header().Set("HX-Trigger", `{"showMessage":"Token revoked"}`)
+	w.WriteHeader(http.StatusNoContent)
+}
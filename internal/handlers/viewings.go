@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/liamwears/reelscore/internal/apperror"
+	"github.com/liamwears/reelscore/internal/middleware"
+	"github.com/liamwears/reelscore/internal/models"
+	"github.com/liamwears/reelscore/internal/services"
+)
+
+// ViewingHandler handles watch diary requests
+type ViewingHandler struct {
+	viewingService *services.ViewingService
+	logger         *slog.Logger
+}
+
+// NewViewingHandler creates a new viewing handler
+func NewViewingHandler(viewingService *services.ViewingService, logger *slog.Logger) *ViewingHandler {
+	return &ViewingHandler{
+		viewingService: viewingService,
+		logger:         logger,
+	}
+}
+
+// List handles GET /api/movies/{id}/viewings
+func (h *ViewingHandler) List(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Get movie ID from path
+	movieID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid movie ID"))
+		return
+	}
+
+	// Call service
+	viewings, err := h.viewingService.List(r.Context(), userID, movieID)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list viewings", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to fetch viewings"))
+		return
+	}
+
+	// Return JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(viewings)
+}
+
+// Create handles POST /api/movies/{id}/viewings
+func (h *ViewingHandler) Create(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Get movie ID from path
+	movieID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid movie ID"))
+		return
+	}
+
+	// Parse request body
+	var input models.CreateViewingInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid request body"))
+		return
+	}
+
+	// Call service
+	viewing, err := h.viewingService.Create(r.Context(), userID, movieID, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			apperror.Write(w, r, apperror.NotFound("Movie not found"))
+		case errors.Is(err, services.ErrInvalidViewingDate):
+			apperror.Write(w, r, apperror.Validation("Invalid watched-on date, expected YYYY-MM-DD", map[string]string{"watchedOn": "Expected YYYY-MM-DD"}))
+		case errors.Is(err, services.ErrInvalidViewingRating):
+			apperror.Write(w, r, apperror.Validation("Rating must be between 0 and 10", map[string]string{"rating": "Must be between 0 and 10"}))
+		default:
+			h.logger.ErrorContext(r.Context(), "Failed to create viewing", "error", err)
+			apperror.Write(w, r, apperror.Internal("Failed to log viewing"))
+		}
+		return
+	}
+
+	// Return created viewing
+	w.Header().Set("HX-Trigger", `{"showMessage":"Viewing added to your diary"}`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(viewing)
+}
+
+// Delete handles DELETE /api/movies/{id}/viewings/{viewingId}
+func (h *ViewingHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	// Get user from context
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Get movie and viewing IDs from path
+	movieID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid movie ID"))
+		return
+	}
+	viewingID, err := uuid.Parse(r.PathValue("viewingId"))
+	if err != nil {
+		apperror.Write(w, r, apperror.BadRequest("Invalid viewing ID"))
+		return
+	}
+
+	// Call service
+	err = h.viewingService.Delete(r.Context(), userID, movieID, viewingID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			apperror.Write(w, r, apperror.NotFound("Viewing not found"))
+			return
+		}
+		h.logger.ErrorContext(r.Context(), "Failed to delete viewing", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to delete viewing"))
+		return
+	}
+
+	// Return success
+	w.Header().Set("HX-Trigger", `{"showMessage":"Viewing deleted"}`)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,141 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextKey is a custom type for context keys to avoid collisions
+type contextKey string
+
+// requestKey is the key for storing the request's log fields in context
+const requestKey contextKey = "logRequest"
+
+// request holds the fields attached to every log record of one HTTP request. Fields
+// learnt deeper in the handler chain, like the user, are written back to it so the
+// access log line sees them too.
+type request struct {
+	mu     sync.Mutex
+	id     string
+	userID string
+	route  string
+}
+
+// New creates a logger writing JSON in production and text otherwise. Records logged
+// with a request's context carry its request ID, user ID and trace ID.
+func New(w io.Writer, level string, production bool) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level)}
+
+	var handler slog.Handler
+	if production {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+
+	return slog.New(contextHandler{handler})
+}
+
+// ParseLevel parses debug, info, warn or error, defaulting to info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// WithRequestID starts the log fields of a request
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestKey, &request{id: requestID})
+}
+
+// RequestID returns the ID of the request a context belongs to
+func RequestID(ctx context.Context) string {
+	req, ok := ctx.Value(requestKey).(*request)
+	if !ok {
+		return ""
+	}
+	return req.id
+}
+
+// SetUserID records the authenticated user of the request
+func SetUserID(ctx context.Context, userID string) {
+	if req, ok := ctx.Value(requestKey).(*request); ok {
+		req.mu.Lock()
+		req.userID = userID
+		req.mu.Unlock()
+	}
+}
+
+// SetRoute records the route pattern that matched the request
+func SetRoute(ctx context.Context, route string) {
+	if req, ok := ctx.Value(requestKey).(*request); ok {
+		req.mu.Lock()
+		req.route = route
+		req.mu.Unlock()
+	}
+}
+
+// Route returns the route pattern recorded for the request
+func Route(ctx context.Context) string {
+	req, ok := ctx.Value(requestKey).(*request)
+	if !ok {
+		return ""
+	}
+	req.mu.Lock()
+	defer req.mu.Unlock()
+	return req.route
+}
+
+// UserID returns the user recorded for the request
+func UserID(ctx context.Context) string {
+	req, ok := ctx.Value(requestKey).(*request)
+	if !ok {
+		return ""
+	}
+	req.mu.Lock()
+	defer req.mu.Unlock()
+	return req.userID
+}
+
+// contextHandler adds the request ID, user ID and trace ID from the context to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle adds the request's fields and passes the record on
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if req, ok := ctx.Value(requestKey).(*request); ok {
+		req.mu.Lock()
+		record.AddAttrs(slog.String("request_id", req.id))
+		if req.userID != "" {
+			record.AddAttrs(slog.String("user_id", req.userID))
+		}
+		req.mu.Unlock()
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs returns a handler with extra attributes that still adds request fields
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a handler with a group that still adds request fields
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Default is the registry served on /metrics, along with Go runtime and process metrics
+var Default = prometheus.NewRegistry()
+
+var factory = promauto.With(Default)
+
+func init() {
+	Default.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var (
+	// HTTPRequests counts handled requests by route pattern, method and status
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "reelscore_http_requests_total",
+		Help: "HTTP requests handled, by route pattern, method and status.",
+	}, []string{"route", "method", "status"})
+	// HTTPDuration observes request latency by route pattern, method and status
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "reelscore_http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds, by route pattern, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// TMDBRequests counts upstream TMDB calls by endpoint and status
+	TMDBRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "reelscore_tmdb_requests_total",
+		Help: "Upstream TMDB requests, by endpoint and HTTP status (0 when no response).",
+	}, []string{"endpoint", "status"})
+	// TMDBDuration observes upstream TMDB latency by endpoint
+	TMDBDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "reelscore_tmdb_request_duration_seconds",
+		Help:    "Upstream TMDB request latency in seconds, by endpoint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+	// TMDBErrors counts failed upstream TMDB calls by endpoint
+	TMDBErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "reelscore_tmdb_request_errors_total",
+		Help: "Upstream TMDB requests that failed or returned a non-200 status, by endpoint.",
+	}, []string{"endpoint"})
+
+	// RedisErrors counts failed Redis commands by command name
+	RedisErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "reelscore_redis_command_errors_total",
+		Help: "Redis commands that returned an error, by command.",
+	}, []string{"command"})
+
+	// RateLimitRejections counts requests rejected by the rate limiter by policy
+	RateLimitRejections = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "reelscore_rate_limit_rejections_total",
+		Help: "Requests rejected by the rate limiter, by policy.",
+	}, []string{"policy"})
+)
+
+// NewGaugeFunc registers a gauge reporting the value returned by fn at scrape time
+func NewGaugeFunc(name, help string, fn func() float64) {
+	factory.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, fn)
+}
+
+// NewCounterFunc registers a counter reporting the value returned by fn at scrape
+// time, for totals kept elsewhere
+func NewCounterFunc(name, help string, fn func() float64) {
+	factory.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, fn)
+}
+
+// Handler serves the default registry. When token is set, scrapers must send it as a
+// bearer token.
+func Handler(token string) http.Handler {
+	metrics := promhttp.HandlerFor(Default, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			scheme, got, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		metrics.ServeHTTP(w, r)
+	})
+}
+
+// ObserveHTTP records a handled request
+func ObserveHTTP(route, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	HTTPRequests.WithLabelValues(route, method, code).Inc()
+	HTTPDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// ObserveTMDB records an upstream TMDB call. A status of 0 means no response arrived.
+func ObserveTMDB(endpoint string, status int, duration time.Duration) {
+	endpoint = TMDBEndpoint(endpoint)
+	TMDBRequests.WithLabelValues(endpoint, strconv.Itoa(status)).Inc()
+	TMDBDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+	if status != 200 {
+		TMDBErrors.WithLabelValues(endpoint).Inc()
+	}
+}
+
+// idSegment matches numeric path segments, like the ID in /movie/603
+var idSegment = regexp.MustCompile(`/\d+`)
+
+// TMDBEndpoint replaces IDs in a TMDB path so each endpoint is one label value
+func TMDBEndpoint(path string) string {
+	return idSegment.ReplaceAllString(path, "/{id}")
+}
@@ -2,10 +2,17 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/liamwears/reelscore/internal/apperror"
 	"github.com/liamwears/reelscore/internal/database"
+	"github.com/liamwears/reelscore/internal/logging"
 	"github.com/liamwears/reelscore/internal/models"
 	"github.com/liamwears/reelscore/internal/services"
 )
@@ -18,24 +25,30 @@ const (
 	UserContextKey ContextKey = "user"
 	// UserIDContextKey is the key for storing user ID in context
 	UserIDContextKey ContextKey = "userID"
+	// APITokenContextKey is the key for storing the API token a request authenticated with
+	APITokenContextKey ContextKey = "apiToken"
+	// SessionIDContextKey is the key for storing the session ID a request authenticated with
+	SessionIDContextKey ContextKey = "sessionID"
 )
 
 // AuthMiddleware handles authentication for protected routes
 type AuthMiddleware struct {
 	sessionStore *database.SessionStore
 	userService  *services.UserService
+	tokenService *services.APITokenService
 	cookieName   string
 	isProduction bool
 }
 
 // NewAuthMiddleware creates a new authentication middleware
-func NewAuthMiddleware(sessionStore *database.SessionStore, userService *services.UserService, cookieName string, isProduction bool) *AuthMiddleware {
+func NewAuthMiddleware(sessionStore *database.SessionStore, userService *services.UserService, tokenService *services.APITokenService, cookieName string, isProduction bool) *AuthMiddleware {
 	if cookieName == "" {
 		cookieName = "session"
 	}
 	return &AuthMiddleware{
 		sessionStore: sessionStore,
 		userService:  userService,
+		tokenService: tokenService,
 		cookieName:   cookieName,
 		isProduction: isProduction,
 	}
@@ -48,7 +61,7 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 		cookie, err := r.Cookie(m.cookieName)
 		if err != nil {
 			// No session cookie, redirect to login
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			redirectToLogin(w, r)
 			return
 		}
 
@@ -56,7 +69,7 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 		userID, err := m.sessionStore.Get(r.Context(), cookie.Value)
 		if err != nil {
 			// Invalid or expired session, redirect to login
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			redirectToLogin(w, r)
 			return
 		}
 
@@ -71,19 +84,31 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 				Path:   "/",
 				MaxAge: -1,
 			})
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			redirectToLogin(w, r)
 			return
 		}
 
-		// Add user to context
+		// Add user and session to context
 		ctx := context.WithValue(r.Context(), UserContextKey, user)
 		ctx = context.WithValue(ctx, UserIDContextKey, userID)
+		ctx = context.WithValue(ctx, SessionIDContextKey, cookie.Value)
+		logging.SetUserID(ctx, userID.String())
 
 		// Call next handler
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// redirectToLogin sends the user to the login page, remembering the page they asked
+// for so they land back on it after signing in
+func redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	target := "/login"
+	if r.Method == http.MethodGet {
+		target += "?return_to=" + url.QueryEscape(r.URL.RequestURI())
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
 // OptionalAuth checks for authentication but doesn't require it
 func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -111,46 +136,132 @@ func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		// Add user to context
+		// Add user and session to context
 		ctx := context.WithValue(r.Context(), UserContextKey, user)
 		ctx = context.WithValue(ctx, UserIDContextKey, userID)
+		ctx = context.WithValue(ctx, SessionIDContextKey, cookie.Value)
+		logging.SetUserID(ctx, userID.String())
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// RequireAuthAPI ensures the user is authenticated for API requests
+// RequireAuthAPI ensures the user is authenticated for API requests, either with the
+// session cookie or a personal access token in the Authorization header
 func (m *AuthMiddleware) RequireAuthAPI(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Bearer tokens take precedence over cookies
+		if secret, ok := BearerToken(r); ok {
+			m.authenticateToken(w, r, secret, next)
+			return
+		}
+
 		// Get session cookie
 		cookie, err := r.Cookie(m.cookieName)
 		if err != nil {
-			http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+			apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 			return
 		}
 
 		// Get user ID from session
 		userID, err := m.sessionStore.Get(r.Context(), cookie.Value)
 		if err != nil {
-			http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+			apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 			return
 		}
 
 		// Get user from database
 		user, err := m.userService.Get(r.Context(), userID)
 		if err != nil {
-			http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+			apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
 			return
 		}
 
-		// Add user to context
+		// Add user and session to context
 		ctx := context.WithValue(r.Context(), UserContextKey, user)
 		ctx = context.WithValue(ctx, UserIDContextKey, userID)
+		ctx = context.WithValue(ctx, SessionIDContextKey, cookie.Value)
+		logging.SetUserID(ctx, userID.String())
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireSessionAPI is RequireAuthAPI for endpoints that personal access tokens may not
+// use, such as managing the tokens themselves
+func (m *AuthMiddleware) RequireSessionAPI(next http.Handler) http.Handler {
+	return m.RequireAuthAPI(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetAPITokenFromContext(r.Context()); ok {
+			apperror.Write(w, r, apperror.Forbidden("This endpoint requires a browser session"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// authenticateToken authenticates a request with a personal access token. Reads need
+// the library:read scope and everything else library:write.
+func (m *AuthMiddleware) authenticateToken(w http.ResponseWriter, r *http.Request, secret string, next http.Handler) {
+	token, err := m.tokenService.Authenticate(r.Context(), secret)
+	if errors.Is(err, services.ErrInvalidAPIToken) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		apperror.Write(w, r, apperror.Unauthorized("Invalid or expired token"))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to check API token", "error", err)
+		apperror.Write(w, r, apperror.Internal("Failed to check token"))
+		return
+	}
+
+	scope := models.ScopeLibraryWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		scope = models.ScopeLibraryRead
+	}
+	if !token.HasScope(scope) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+string(scope)+`"`)
+		apperror.Write(w, r, apperror.Forbidden("Token is missing the "+string(scope)+" scope"))
+		return
+	}
+
+	// Get user from database
+	user, err := m.userService.Get(r.Context(), token.UserID)
+	if err != nil {
+		apperror.Write(w, r, apperror.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// Add user and token to context
+	ctx := context.WithValue(r.Context(), UserContextKey, user)
+	ctx = context.WithValue(ctx, UserIDContextKey, user.ID)
+	ctx = context.WithValue(ctx, APITokenContextKey, token)
+	logging.SetUserID(ctx, user.ID.String())
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// BearerToken returns the token from an "Authorization: Bearer" header
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// GetAPITokenFromContext retrieves the API token a request authenticated with
+func GetAPITokenFromContext(ctx context.Context) (*models.APIToken, bool) {
+	token, ok := ctx.Value(APITokenContextKey).(*models.APIToken)
+	return token, ok
+}
+
+// GetSessionIDFromContext retrieves the session ID a request authenticated with
+func GetSessionIDFromContext(ctx context.Context) (string, bool) {
+	sessionID, ok := ctx.Value(SessionIDContextKey).(string)
+	return sessionID, ok
+}
+
 // GetUserFromContext retrieves the user from request context
 func GetUserFromContext(ctx context.Context) (*models.User, bool) {
 	user, ok := ctx.Value(UserContextKey).(*models.User)
@@ -190,3 +301,42 @@ func (m *AuthMiddleware) ClearSessionCookie(w http.ResponseWriter) {
 	}
 	http.SetCookie(w, cookie)
 }
+
+// SetLoginCookie sets the short-lived pre-login cookie binding an OAuth login
+// attempt to this browser
+func (m *AuthMiddleware) SetLoginCookie(w http.ResponseWriter, loginID string, ttl time.Duration) {
+	cookie := &http.Cookie{
+		Name:     m.cookieName + "_login",
+		Value:    loginID,
+		Path:     "/auth",
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   m.isProduction,
+		// Lax so the cookie is sent on the provider's top-level redirect back
+		SameSite: http.SameSiteLaxMode,
+	}
+	http.SetCookie(w, cookie)
+}
+
+// GetLoginCookie returns the pre-login ID, if any
+func (m *AuthMiddleware) GetLoginCookie(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(m.cookieName + "_login")
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+// ClearLoginCookie clears the pre-login cookie
+func (m *AuthMiddleware) ClearLoginCookie(w http.ResponseWriter) {
+	cookie := &http.Cookie{
+		Name:     m.cookieName + "_login",
+		Value:    "",
+		Path:     "/auth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   m.isProduction,
+		SameSite: http.SameSiteLaxMode,
+	}
+	http.SetCookie(w, cookie)
+}
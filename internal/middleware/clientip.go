@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPContextKey is the key for storing the resolved client IP in context
+const ClientIPContextKey ContextKey = "clientIP"
+
+// ClientIPResolver works out the address of the client that sent a request. Forwarding
+// headers are only believed when they were added by one of the trusted proxies, as
+// anyone can send them.
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+	// header is the one forwarding header the trusted proxies set. Proxies pass the
+	// other one through from the client untouched, so it is never read.
+	header string
+}
+
+// NewClientIPResolver creates a resolver trusting proxies in the given CIDR ranges to
+// set header, either X-Forwarded-For or Forwarded. Bare IP addresses are accepted as
+// single-address ranges.
+func NewClientIPResolver(trustedProxies []string, header string) (*ClientIPResolver, error) {
+	header = http.CanonicalHeaderKey(header)
+	if header != "X-Forwarded-For" && header != "Forwarded" {
+		return nil, fmt.Errorf("unsupported forwarding header %q", header)
+	}
+
+	resolver := &ClientIPResolver{header: header}
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		resolver.trusted = append(resolver.trusted, prefix.Masked())
+	}
+	return resolver, nil
+}
+
+// Resolve stores the client IP of each request in its context for ClientIP
+func (c *ClientIPResolver) Resolve(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ClientIPContextKey, c.ClientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientIP resolves the client IP of a request. Starting from the connection's peer,
+// it walks the forwarding chain from the nearest hop outwards and stops at the first
+// address that isn't a trusted proxy.
+func (c *ClientIPResolver) ClientIP(r *http.Request) string {
+	remote, ok := parseHost(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !c.isTrusted(remote) {
+		return remote.String()
+	}
+
+	var hops []string
+	if c.header == "Forwarded" {
+		hops = forwardedFor(r.Header.Values("Forwarded"))
+	} else {
+		hops = xForwardedFor(r.Header.Values("X-Forwarded-For"))
+	}
+
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHost(hops[i])
+		if !ok {
+			// Obfuscated or garbled hops can't be followed any further
+			break
+		}
+		client = addr
+		if !c.isTrusted(addr) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+// isTrusted reports whether an address belongs to a trusted proxy
+func (c *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range c.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client IP stored by ClientIPResolver.Resolve, falling back to
+// the connection's peer address
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(ClientIPContextKey).(string); ok {
+		return ip
+	}
+	if addr, ok := parseHost(r.RemoteAddr); ok {
+		return addr.String()
+	}
+	return r.RemoteAddr
+}
+
+// xForwardedFor splits X-Forwarded-For headers into hops, client first
+func xForwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// forwardedFor returns the for= node of each element of RFC 7239 Forwarded headers,
+// client first
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			node := ""
+			for _, pair := range strings.Split(element, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					node = strings.Trim(val, `"`)
+				}
+			}
+			hops = append(hops, node)
+		}
+	}
+	return hops
+}
+
+// parseHost parses an IP address with an optional port, as found in RemoteAddr and
+// forwarding headers ("192.0.2.1", "192.0.2.1:80", "[2001:db8::1]:80", "2001:db8::1")
+func parseHost(hostport string) (netip.Addr, bool) {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		remoteAddr string
+		headers    map[string][]string
+		want       string
+	}{
+		{
+			name:       "untrusted peer ignores headers",
+			header:     "X-Forwarded-For",
+			remoteAddr: "203.0.113.9:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4"}},
+			want:       "203.0.113.9",
+		},
+		{
+			name:       "spoofed Forwarded behind an X-Forwarded-For proxy",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.2:5000",
+			headers: map[string][]string{
+				"Forwarded":       {"for=1.2.3.4"},
+				"X-Forwarded-For": {"198.51.100.7"},
+			},
+			want: "198.51.100.7",
+		},
+		{
+			name:       "spoofed X-Forwarded-For behind a Forwarded proxy",
+			header:     "Forwarded",
+			remoteAddr: "10.0.0.2:5000",
+			headers: map[string][]string{
+				"Forwarded":       {"for=198.51.100.7;proto=https"},
+				"X-Forwarded-For": {"1.2.3.4"},
+			},
+			want: "198.51.100.7",
+		},
+		{
+			name:       "no Forwarded falls back to the peer, not X-Forwarded-For",
+			header:     "Forwarded",
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4"}},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "walks right to left through trusted hops",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.7, 10.1.2.3"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "hops split across header lines",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4", "198.51.100.7", "10.1.2.3"}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "every hop trusted gives the leftmost",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.9.9.9, 10.1.2.3"}},
+			want:       "10.9.9.9",
+		},
+		{
+			name:       "garbled hop stops the walk",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.7, bogus, 10.1.2.3"}},
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "IPv6 X-Forwarded-For hop",
+			header:     "X-Forwarded-For",
+			remoteAddr: "[fd00::2]:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"2001:db8::7"}},
+			want:       "2001:db8::7",
+		},
+		{
+			name:       "bracketed IPv6 for= node with port",
+			header:     "Forwarded",
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"Forwarded": {`for="[2001:db8::7]:4711", for=10.1.2.3`}},
+			want:       "2001:db8::7",
+		},
+		{
+			name:       "IPv4 for= node with port",
+			header:     "Forwarded",
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"Forwarded": {`For="198.51.100.7:4711";proto=https`}},
+			want:       "198.51.100.7",
+		},
+		{
+			name:       "obfuscated for= node stops the walk",
+			header:     "Forwarded",
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"Forwarded": {"for=198.51.100.7, for=_hidden, for=10.1.2.3"}},
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "IPv4-mapped peer is unmapped",
+			header:     "X-Forwarded-For",
+			remoteAddr: "[::ffff:203.0.113.9]:5000",
+			want:       "203.0.113.9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, err := NewClientIPResolver([]string{"10.0.0.0/8", "fd00::/8"}, tt.header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.headers {
+				for _, value := range values {
+					r.Header.Add(name, value)
+				}
+			}
+
+			if got := resolver.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewClientIPResolver(t *testing.T) {
+	if _, err := NewClientIPResolver([]string{"192.0.2.1", " 10.0.0.0/8 ", ""}, "x-forwarded-for"); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+	if _, err := NewClientIPResolver([]string{"not-a-cidr"}, "X-Forwarded-For"); err == nil {
+		t.Error("invalid proxy accepted")
+	}
+	if _, err := NewClientIPResolver(nil, "X-Real-IP"); err == nil {
+		t.Error("unsupported header accepted")
+	}
+}
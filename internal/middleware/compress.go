@@ -0,0 +1,269 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressMinSize is the smallest body worth compressing; below it the encoding's
+// overhead outweighs the savings
+const compressMinSize = 1024
+
+// brotliLevel trades some ratio for speed, as responses are compressed on the fly
+const brotliLevel = 5
+
+// compressibleTypes are the content type prefixes that compress well
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// encoder is a compressing writer that can be reset and reused
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders reuses compressing writers per content coding, as they are expensive to
+// allocate
+var encoders = map[string]*sync.Pool{
+	"br": {
+		New: func() interface{} {
+			return brotli.NewWriterLevel(io.Discard, brotliLevel)
+		},
+	},
+	"gzip": {
+		New: func() interface{} {
+			return gzip.NewWriter(io.Discard)
+		},
+	},
+}
+
+// Compress brotli or gzip compresses HTML, JSON and other text responses for clients
+// that accept it. Small bodies, ranges and responses the handler encoded itself are
+// left alone.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if r.Method == http.MethodHead || r.Header.Get("Range") != "" || encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, statusCode: http.StatusOK, encoding: encoding}
+		next.ServeHTTP(cw, r)
+
+		// Not deferred: after a panic the buffered response is dropped so that
+		// Recover can still send its own
+		cw.Close()
+	})
+}
+
+// compressWriter buffers the start of a response to decide whether to compress it,
+// then streams it through the negotiated encoding or unchanged
+type compressWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	// encoding is the content coding the client accepts, br or gzip
+	encoding string
+	enc      encoder
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	// Informational responses go straight out and don't end the headers
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.statusCode = code
+	cw.wroteHeader = true
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		return cw.write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) < compressMinSize {
+		return len(b), nil
+	}
+	if err := cw.decide(false); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush sends what is buffered so streamed responses aren't held back
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			cw.WriteHeader(http.StatusOK)
+		}
+		if err := cw.decide(true); err != nil {
+			return
+		}
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close writes out a response still buffered and finishes the compressed stream
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			// The handler wrote nothing, let net/http send its default response
+			return nil
+		}
+		if err := cw.decide(false); err != nil {
+			return err
+		}
+	}
+	if cw.enc == nil {
+		return nil
+	}
+
+	err := cw.enc.Close()
+	cw.enc.Reset(io.Discard)
+	encoders[cw.encoding].Put(cw.enc)
+	cw.enc = nil
+	return err
+}
+
+// decide sends the headers, compressed or not, followed by the buffered body. When
+// flushing, the buffer may be only the start of a longer response, so its size
+// doesn't rule out compression.
+func (cw *compressWriter) decide(flushing bool) error {
+	cw.decided = true
+
+	h := cw.Header()
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if cw.shouldCompress(flushing) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoding)
+		// A strong ETag no longer matches the encoded bytes
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.enc = encoders[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := cw.write(buf)
+	return err
+}
+
+// shouldCompress reports whether the response is big enough, compressible and not
+// already encoded
+func (cw *compressWriter) shouldCompress(flushing bool) bool {
+	h := cw.Header()
+	if len(cw.buf) < compressMinSize && !flushing {
+		return false
+	}
+	if cw.statusCode < 200 || cw.statusCode == http.StatusNoContent || cw.statusCode == http.StatusNotModified ||
+		cw.statusCode == http.StatusPartialContent {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	if length, err := strconv.Atoi(h.Get("Content-Length")); err == nil && length < compressMinSize {
+		return false
+	}
+
+	contentType := h.Get("Content-Type")
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// write writes body bytes through the encoder when compressing
+func (cw *compressWriter) write(b []byte) (int, error) {
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// negotiateEncoding picks br or gzip from an Accept-Encoding header by q-value,
+// preferring br when the client rates both the same. It returns "" when the client
+// accepts neither.
+func negotiateEncoding(header string) string {
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		weights[coding] = qValue(params)
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{"br", "gzip"} {
+		q, ok := weights[coding]
+		if !ok {
+			// A wildcard covers the codings the client didn't list
+			q = weights["*"]
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// qValue returns the weight in a coding's parameters, 1 when it has none
+func qValue(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "q") {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 {
+			return 0
+		}
+		return min(q, 1)
+	}
+	return 1
+}
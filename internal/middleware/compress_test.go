@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"br", "br"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=1.0, gzip;q=0.8", "br"},
+		{"gzip;q=0.8, br;q=0.8", "br"},
+		{"br;q=0, gzip;q=0", ""},
+		{"BR ; Q=0.9, gzip;q=0.1", "br"},
+		{"*", "br"},
+		{"*;q=0.5, br;q=0", "gzip"},
+		{"gzip;q=bogus", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := negotiateEncoding(tt.header); got != tt.want {
+				t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+// compress serves body through Compress with the given Accept-Encoding, letting
+// handle set headers first
+func compress(t *testing.T, acceptEncoding, body string, handle func(h http.Header)) *httptest.ResponseRecorder {
+	t.Helper()
+
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if handle != nil {
+			handle(w.Header())
+		}
+		io.WriteString(w, body)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/api/movies", nil)
+	r.Header.Set("Accept-Encoding", acceptEncoding)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestCompressEncodings(t *testing.T) {
+	body := strings.Repeat(`{"title":"Heat","year":1995},`, 100)
+	tests := []struct {
+		acceptEncoding string
+		want           string
+		decode         func(io.Reader) (io.Reader, error)
+	}{
+		{"gzip, br", "br", func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+		{"br;q=0.5, gzip", "gzip", func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			w := compress(t, tt.acceptEncoding, body, nil)
+
+			if got := w.Header().Get("Content-Encoding"); got != tt.want {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.want)
+			}
+			if w.Body.Len() >= len(body) {
+				t.Errorf("compressed body is %d bytes, not smaller than %d", w.Body.Len(), len(body))
+			}
+			r, err := tt.decode(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != body {
+				t.Error("decoded body differs from the original")
+			}
+		})
+	}
+}
+
+func TestCompressSkipsSmallBodies(t *testing.T) {
+	body := strings.Repeat("a", compressMinSize-1)
+	w := compress(t, "br, gzip", body, nil)
+
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none below compressMinSize", got)
+	}
+	if w.Body.String() != body {
+		t.Error("body was changed")
+	}
+}
+
+func TestCompressSkipsEncodedResponses(t *testing.T) {
+	var encoded bytes.Buffer
+	gz := gzip.NewWriter(&encoded)
+	for i := range 4 * compressMinSize {
+		fmt.Fprintf(gz, "%d,", i)
+	}
+	gz.Close()
+	body := encoded.String()
+	if len(body) < compressMinSize {
+		t.Fatalf("encoded body is %d bytes, want at least %d", len(body), compressMinSize)
+	}
+
+	w := compress(t, "br, gzip", body, func(h http.Header) {
+		h.Set("Content-Encoding", "gzip")
+	})
+
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("Content-Encoding = %q, want the handler's gzip", got)
+	}
+	if w.Body.String() != body {
+		t.Error("an already encoded body was encoded again")
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"mime"
+	"net/http"
+
+	"github.com/liamwears/reelscore/internal/apperror"
+)
+
+const (
+	// CSRFTokenContextKey is the key for storing the session's CSRF token in context
+	CSRFTokenContextKey ContextKey = "csrfToken"
+
+	// CSRFHeader is the header HTMX sends the CSRF token in
+	CSRFHeader = "X-CSRF-Token"
+	// CSRFFormField is the form field plain HTML forms send the CSRF token in
+	CSRFFormField = "csrf_token"
+)
+
+// CSRF rejects state-changing requests authenticated by the session cookie unless they
+// carry the session's CSRF token. Tokens are derived from the session ID, so each
+// session has its own token without storing it anywhere.
+type CSRF struct {
+	secret     []byte
+	cookieName string
+}
+
+// NewCSRF creates a new CSRF middleware keyed with the server's secret
+func NewCSRF(secret, cookieName string) *CSRF {
+	if cookieName == "" {
+		cookieName = "session"
+	}
+	return &CSRF{
+		secret:     []byte(secret),
+		cookieName: cookieName,
+	}
+}
+
+// Protect adds the session's CSRF token to the request context and checks it on
+// state-changing requests
+func (c *CSRF) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Bearer tokens aren't sent automatically by browsers, so they can't be forged
+		// cross-site. RequireAuthAPI ignores the cookie when one is present.
+		if _, ok := BearerToken(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Without a session there is nothing to forge
+		cookie, err := r.Cookie(c.cookieName)
+		if err != nil || cookie.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := c.Token(cookie.Value)
+		if !isSafeMethod(r.Method) && !hmac.Equal([]byte(requestCSRFToken(r)), []byte(token)) {
+			apperror.Write(w, r, apperror.Forbidden("Invalid or missing CSRF token"))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), CSRFTokenContextKey, token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Token returns the CSRF token of a session
+func (c *CSRF) Token(sessionID string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte("csrf:" + sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// requestCSRFToken returns the token sent with a request, from the HTMX header or a
+// url-encoded form field
+func requestCSRFToken(r *http.Request) string {
+	if token := r.Header.Get(CSRFHeader); token != "" {
+		return token
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/x-www-form-urlencoded" {
+		return r.PostFormValue(CSRFFormField)
+	}
+	return ""
+}
+
+// isSafeMethod reports whether a method is read-only and so needs no CSRF check
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// GetCSRFTokenFromContext retrieves the session's CSRF token from request context
+func GetCSRFTokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(CSRFTokenContextKey).(string)
+	return token, ok
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRFProtect(t *testing.T) {
+	csrf := NewCSRF("test-secret", "session")
+	token := csrf.Token("session-1")
+
+	tests := []struct {
+		name    string
+		method  string
+		session string
+		header  string
+		form    url.Values
+		bearer  bool
+		want    int
+	}{
+		{name: "missing token", method: http.MethodPost, session: "session-1", want: http.StatusForbidden},
+		{name: "wrong token", method: http.MethodPost, session: "session-1", header: "not-the-token", want: http.StatusForbidden},
+		{name: "token in header", method: http.MethodPost, session: "session-1", header: token, want: http.StatusOK},
+		{name: "token in form field", method: http.MethodPost, session: "session-1", form: url.Values{CSRFFormField: {token}}, want: http.StatusOK},
+		{name: "wrong token in form field", method: http.MethodPost, session: "session-1", form: url.Values{CSRFFormField: {"not-the-token"}}, want: http.StatusForbidden},
+		{name: "another session's token", method: http.MethodPost, session: "session-2", header: token, want: http.StatusForbidden},
+		{name: "delete without token", method: http.MethodDelete, session: "session-1", want: http.StatusForbidden},
+		{name: "GET skips the check", method: http.MethodGet, session: "session-1", want: http.StatusOK},
+		{name: "HEAD skips the check", method: http.MethodHead, session: "session-1", want: http.StatusOK},
+		{name: "bearer request skips the check", method: http.MethodPost, session: "session-1", bearer: true, want: http.StatusOK},
+		{name: "no session skips the check", method: http.MethodPost, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotToken string
+			handler := csrf.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotToken, _ = GetCSRFTokenFromContext(r.Context())
+			}))
+
+			var r *http.Request
+			if tt.form != nil {
+				r = httptest.NewRequest(tt.method, "/movies", strings.NewReader(tt.form.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				r = httptest.NewRequest(tt.method, "/movies", nil)
+			}
+			if tt.session != "" {
+				r.AddCookie(&http.Cookie{Name: "session", Value: tt.session})
+			}
+			if tt.header != "" {
+				r.Header.Set(CSRFHeader, tt.header)
+			}
+			if tt.bearer {
+				r.Header.Set("Authorization", "Bearer rs_test")
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			// Session requests that get through carry the session's token for templates
+			if w.Code == http.StatusOK && tt.session != "" && !tt.bearer && gotToken != csrf.Token(tt.session) {
+				t.Errorf("context token = %q, want the session's token", gotToken)
+			}
+		})
+	}
+}
+
+func TestCSRFTokenPerSession(t *testing.T) {
+	csrf := NewCSRF("test-secret", "")
+	if csrf.Token("session-1") != csrf.Token("session-1") {
+		t.Error("token isn't stable for a session")
+	}
+	if csrf.Token("session-1") == csrf.Token("session-2") {
+		t.Error("two sessions share a token")
+	}
+	if NewCSRF("other-secret", "").Token("session-1") == csrf.Token("session-1") {
+		t.Error("token doesn't depend on the secret")
+	}
+}
@@ -1,16 +1,25 @@
 package middleware
 
 import (
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/liamwears/reelscore/internal/logging"
+	"github.com/liamwears/reelscore/internal/metrics"
 )
 
-// responseWriter wraps http.ResponseWriter to capture status code
+// RequestIDHeader carries the request ID from clients and proxies and back in responses
+const RequestIDHeader = "X-Request-ID"
+
+// responseWriter wraps http.ResponseWriter to capture status code and response size
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
 	written    bool
+	bytes      int
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -25,15 +34,31 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	if !rw.written {
 		rw.WriteHeader(http.StatusOK)
 	}
-	return rw.ResponseWriter.Write(b)
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
 }
 
-// Logger logs HTTP requests
-func Logger(logger *log.Logger) func(http.Handler) http.Handler {
+// Logger logs and counts HTTP requests, tagging each with a request ID that is
+// propagated through the context to every log record written while handling it
+func Logger(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 
+			// Reuse the ID from a proxy so logs can be followed across services
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+			ctx := logging.WithRequestID(r.Context(), requestID)
+
 			// Wrap response writer to capture status code
 			wrapped := &responseWriter{
 				ResponseWriter: w,
@@ -41,18 +66,61 @@ func Logger(logger *log.Logger) func(http.Handler) http.Handler {
 			}
 
 			// Call next handler
-			next.ServeHTTP(wrapped, r)
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
 
-			// Log request
+			// Log and count request
 			duration := time.Since(start)
-			logger.Printf(
-				"%s %s %d %s %s",
-				r.Method,
-				r.RequestURI,
-				wrapped.statusCode,
-				duration,
-				r.RemoteAddr,
+			route := logging.Route(ctx)
+			if route == "" {
+				route = "unmatched"
+			}
+			metrics.ObserveHTTP(route, r.Method, wrapped.statusCode, duration)
+			level := slog.LevelInfo
+			if wrapped.statusCode >= 500 {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(ctx, level, "request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+				slog.Int("status", wrapped.statusCode),
+				slog.Int("bytes", wrapped.bytes),
+				slog.Duration("latency", duration),
+				slog.String("ip", ClientIP(r)),
 			)
 		})
 	}
 }
+
+// RecordRoute wraps the router so the route pattern it matched is logged and names the
+// request's span. The router sets the pattern on the request it was given, so it is
+// read once routing is done, even when the handler panicked.
+func RecordRoute(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			logging.SetRoute(r.Context(), r.Pattern)
+			traceRoute(r)
+		}()
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// validRequestID accepts short IDs of visible ASCII so they are safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
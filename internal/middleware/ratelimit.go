@@ -2,49 +2,119 @@ package middleware
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/liamwears/reelscore/internal/apperror"
+	"github.com/liamwears/reelscore/internal/metrics"
 	"github.com/redis/go-redis/v9"
 )
 
+// RateLimitPolicy is a named request budget. Each policy counts requests separately,
+// so a client spending its TMDB budget can still read its library.
+type RateLimitPolicy struct {
+	Name        string
+	MaxRequests int
+	Window      time.Duration
+}
+
+// rateLimitTimeout bounds how long a Redis rate limit check may take
+const rateLimitTimeout = 500 * time.Millisecond
+
+// RateLimiterConfig holds rate limiter configuration
+type RateLimiterConfig struct {
+	// Enabled turns limiting on. A disabled limiter lets every request through without
+	// setting rate limit headers.
+	Enabled bool
+	// FailureMode decides how requests are limited while Redis is unavailable
+	FailureMode RateLimitFailureMode
+	// BreakerThreshold is how many consecutive Redis failures open the circuit breaker
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open before Redis is tried again
+	BreakerCooldown time.Duration
+}
+
 // RateLimiter provides rate limiting functionality
 type RateLimiter struct {
 	redis       *redis.Client
-	maxRequests int
-	window      time.Duration
-	isProduction bool
+	enabled     bool
+	failureMode RateLimitFailureMode
+	breaker     *circuitBreaker
+	local       *localLimiter
 }
 
 // NewRateLimiter creates a new rate limiter
-func NewRateLimiter(redis *redis.Client, maxRequests int, window time.Duration, isProduction bool) *RateLimiter {
+func NewRateLimiter(redis *redis.Client, config RateLimiterConfig) *RateLimiter {
+	if !config.FailureMode.IsValid() {
+		config.FailureMode = RateLimitFailLocal
+	}
+	if config.BreakerThreshold == 0 {
+		config.BreakerThreshold = 5
+	}
+	if config.BreakerCooldown == 0 {
+		config.BreakerCooldown = 30 * time.Second
+	}
 	return &RateLimiter{
 		redis:       redis,
-		maxRequests: maxRequests,
-		window:      window,
-		isProduction: isProduction,
+		enabled:     config.Enabled,
+		failureMode: config.FailureMode,
+		breaker:     newCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown),
+		local:       newLocalLimiter(),
 	}
 }
 
-// Limit returns a middleware that rate limits requests
-func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
+// rateLimitResult is the outcome of counting a request against a policy
+type rateLimitResult struct {
+	allowed   bool
+	remaining int
+	// reset is how long until the oldest request in the window expires
+	reset time.Duration
+}
+
+// Limit rate limits requests to next under the given policy. It keys on the token or user
+// the auth middleware put in the context, so it must be wrapped inside RequireAuthAPI or
+// OptionalAuth; anything unauthenticated is limited by client IP.
+func (rl *RateLimiter) Limit(policy RateLimitPolicy, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get identifier (user ID if authenticated, IP otherwise)
+		if !rl.enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Get identifier (token or user ID if authenticated, IP otherwise)
 		identifier := rl.getIdentifier(r)
 
-		// Check rate limit
-		allowed, err := rl.checkRateLimit(r.Context(), identifier)
-		if err != nil {
-			// Log error but don't block request
-			http.Error(w, `{"error":"Rate limit check failed"}`, http.StatusInternalServerError)
-			return
+		// Check rate limit, falling back while Redis is unavailable
+		result, ok := rl.check(r.Context(), policy, identifier)
+		if !ok {
+			switch rl.failureMode {
+			case RateLimitFailOpen:
+				next.ServeHTTP(w, r)
+				return
+			case RateLimitFailClosed:
+				metrics.RateLimitRejections.WithLabelValues(policy.Name).Inc()
+				apperror.Write(w, r, apperror.Unavailable("Service temporarily unavailable"))
+				return
+			}
+			result = rl.local.take(policy.Name+":"+identifier, policy)
 		}
 
-		if !allowed {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-			fmt.Fprintf(w, `{"error":"Too many requests. Please try again later."}`)
+		// Advertise the budget using the IETF RateLimit header fields
+		reset := strconv.Itoa(int(math.Ceil(result.reset.Seconds())))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.MaxRequests, int(policy.Window.Seconds())))
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(policy.MaxRequests))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.remaining))
+		w.Header().Set("RateLimit-Reset", reset)
+
+		if !result.allowed {
+			metrics.RateLimitRejections.WithLabelValues(policy.Name).Inc()
+			w.Header().Set("Retry-After", reset)
+			apperror.Write(w, r, apperror.RateLimited("Too many requests. Please try again later."))
 			return
 		}
 
@@ -53,57 +123,109 @@ func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
 	})
 }
 
-// getIdentifier returns the identifier for rate limiting
+// getIdentifier returns the identifier for rate limiting. Only identities the auth
+// middleware has verified are trusted; a bearer header alone is not.
 func (rl *RateLimiter) getIdentifier(r *http.Request) string {
+	// Scripts using a personal access token get their own budget per token
+	if token, ok := GetAPITokenFromContext(r.Context()); ok {
+		return fmt.Sprintf("token:%s", token.ID.String())
+	}
+
 	// Try to get user ID from context
 	if userID, ok := GetUserIDFromContext(r.Context()); ok {
 		return fmt.Sprintf("user:%s", userID.String())
 	}
 
 	// Fallback to IP address
-	ip := r.Header.Get("X-Forwarded-For")
-	if ip == "" {
-		ip = r.RemoteAddr
-	}
-	return fmt.Sprintf("ip:%s", ip)
+	return fmt.Sprintf("ip:%s", ClientIP(r))
 }
 
-// checkRateLimit checks if the request should be allowed
-func (rl *RateLimiter) checkRateLimit(ctx context.Context, identifier string) (bool, error) {
-	// Skip rate limiting in local/dev mode for easier testing
-	if !rl.isProduction {
-		return true, nil
+// check counts the request in Redis through the circuit breaker. It reports false when
+// Redis couldn't be used.
+func (rl *RateLimiter) check(ctx context.Context, policy RateLimitPolicy, identifier string) (rateLimitResult, bool) {
+	if !rl.breaker.allow() {
+		return rateLimitResult{}, false
 	}
 
-	key := fmt.Sprintf("ratelimit:%s", identifier)
-	now := time.Now().Unix()
-	windowStart := now - int64(rl.window.Seconds())
+	// Don't let a hanging Redis hold up the request
+	redisCtx, cancel := context.WithTimeout(ctx, rateLimitTimeout)
+	defer cancel()
 
-	// Use Redis sorted set for sliding window
-	pipe := rl.redis.Pipeline()
-
-	// Remove old entries outside the window
-	pipe.ZRemRangeByScore(ctx, key, "0", fmt.Sprintf("%d", windowStart))
-
-	// Count requests in current window
-	countCmd := pipe.ZCard(ctx, key)
+	result, err := rl.checkRateLimit(redisCtx, policy, identifier)
+	if err != nil {
+		// A cancelled request says nothing about Redis
+		if ctx.Err() == nil {
+			rl.breaker.failure(err)
+		} else {
+			rl.breaker.release()
+		}
+		return rateLimitResult{}, false
+	}
 
-	// Add current request
-	pipe.ZAdd(ctx, key, redis.Z{
-		Score:  float64(now),
-		Member: fmt.Sprintf("%d", now),
-	})
+	rl.breaker.success()
+	return result, true
+}
 
-	// Set expiry on the key
-	pipe.Expire(ctx, key, rl.window)
+// slidingWindowScript trims the window, then records the request only if it is under
+// the limit, so rejected requests don't keep a client locked out. It returns whether
+// the request was admitted, the count in the window and the oldest request's score.
+var slidingWindowScript = redis.NewScript(`
+local key, now, window, limit, member = KEYS[1], tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), ARGV[4]
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, member)
+	redis.call('PEXPIRE', key, window)
+	count = count + 1
+	allowed = 1
+end
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+return {allowed, count, oldest[2] or tostring(now)}
+`)
+
+// checkRateLimit counts the request against the policy's sliding window
+func (rl *RateLimiter) checkRateLimit(ctx context.Context, policy RateLimitPolicy, identifier string) (rateLimitResult, error) {
+	key := fmt.Sprintf("ratelimit:%s:%s", policy.Name, identifier)
+	now := time.Now()
+
+	// Members must be unique or requests in the same instant collapse into one
+	member, err := uniqueMember(now)
+	if err != nil {
+		return rateLimitResult{}, err
+	}
 
-	// Execute pipeline
-	_, err := pipe.Exec(ctx)
+	// Trim, count and admit atomically so concurrent requests can't overshoot the limit
+	res, err := slidingWindowScript.Run(ctx, rl.redis, []string{key},
+		now.UnixMilli(), policy.Window.Milliseconds(), policy.MaxRequests, member).Slice()
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+	if len(res) != 3 {
+		return rateLimitResult{}, fmt.Errorf("unexpected rate limit script result %v", res)
+	}
+	allowed, _ := res[0].(int64)
+	count, _ := res[1].(int64)
+	oldest, _ := res[2].(string)
+	oldestMillis, err := strconv.ParseFloat(oldest, 64)
 	if err != nil {
-		return false, err
+		return rateLimitResult{}, fmt.Errorf("parse oldest rate limit entry: %w", err)
 	}
 
-	// Check if count exceeds limit
-	count := countCmd.Val()
-	return count < int64(rl.maxRequests), nil
+	// The window frees up once its oldest request expires
+	expires := time.UnixMilli(int64(oldestMillis)).Add(policy.Window)
+	return rateLimitResult{
+		allowed:   allowed == 1,
+		remaining: max(policy.MaxRequests-int(count), 0),
+		reset:     max(time.Until(expires), 0),
+	}, nil
+}
+
+// uniqueMember returns a sorted set member for a request made at now
+func uniqueMember(now time.Time) (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%s", now.UnixNano(), hex.EncodeToString(b)), nil
 }
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitFailureMode decides what happens to requests while Redis is unavailable
+type RateLimitFailureMode string
+
+const (
+	// RateLimitFailLocal limits requests with in-process token buckets, per server
+	RateLimitFailLocal RateLimitFailureMode = "local"
+	// RateLimitFailOpen lets every request through unlimited
+	RateLimitFailOpen RateLimitFailureMode = "open"
+	// RateLimitFailClosed rejects every rate limited request
+	RateLimitFailClosed RateLimitFailureMode = "closed"
+)
+
+// IsValid checks if the failure mode is valid
+func (m RateLimitFailureMode) IsValid() bool {
+	return m == RateLimitFailLocal || m == RateLimitFailOpen || m == RateLimitFailClosed
+}
+
+// circuitBreaker stops calling Redis after repeated failures. Once the cooldown has
+// passed a single request is let through to probe it, closing the breaker on success.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// newCircuitBreaker creates a breaker that opens after threshold consecutive failures
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow reports whether Redis should be called for this request
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// success records a successful call, closing the breaker
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures >= b.threshold {
+		slog.Info("Rate limiter: Redis reachable again, closing circuit breaker")
+	}
+	b.failures = 0
+	b.probing = false
+}
+
+// release ends a call that says nothing about Redis, such as one the client cancelled.
+// A half-open probe is given up so the next request probes again.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// failure records a failed call, opening the breaker once the threshold is reached
+func (b *circuitBreaker) failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		if b.failures == b.threshold {
+			slog.Warn("Rate limiter: Redis failing, opening circuit breaker", "error", err, "cooldown", b.cooldown)
+		}
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// tokenBucket is an in-process bucket refilled continuously up to its capacity
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// window is how long the bucket takes to refill from empty
+	window time.Duration
+}
+
+// localLimiter rate limits with token buckets held in memory while Redis is down.
+// Buckets are per server, so the effective limit is multiplied by the number of
+// servers.
+type localLimiter struct {
+	mu         sync.Mutex
+	buckets    map[string]*tokenBucket
+	lastPruned time.Time
+}
+
+// newLocalLimiter creates an empty in-process limiter
+func newLocalLimiter() *localLimiter {
+	return &localLimiter{
+		buckets:    make(map[string]*tokenBucket),
+		lastPruned: time.Now(),
+	}
+}
+
+// take spends a token from the bucket of key, refilled at policy.MaxRequests per
+// policy.Window
+func (l *localLimiter) take(key string, policy RateLimitPolicy) rateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	capacity := float64(policy.MaxRequests)
+	rate := capacity / policy.Window.Seconds()
+
+	l.prune(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, last: now, window: policy.Window}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+
+	result := rateLimitResult{allowed: bucket.tokens >= 1}
+	if result.allowed {
+		bucket.tokens--
+	}
+	result.remaining = int(bucket.tokens)
+	// Time until the bucket is full again, or until the next token when empty
+	if result.allowed {
+		result.reset = time.Duration((capacity - bucket.tokens) / rate * float64(time.Second))
+	} else {
+		result.reset = time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+	}
+
+	return result
+}
+
+// prune drops buckets idle for long enough to have refilled, at most once a minute
+func (l *localLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPruned) < time.Minute {
+		return
+	}
+	l.lastPruned = now
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.last) > bucket.window {
+			delete(l.buckets, key)
+		}
+	}
+}
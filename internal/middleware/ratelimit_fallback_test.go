@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var errRedisDown = errors.New("redis down")
+
+func TestCircuitBreaker(t *testing.T) {
+	b := newCircuitBreaker(2, time.Minute)
+
+	// Closed: failures below the threshold keep calling Redis
+	if !b.allow() {
+		t.Fatal("closed breaker refused a call")
+	}
+	b.failure(errRedisDown)
+	if !b.allow() {
+		t.Fatal("breaker opened before the threshold")
+	}
+	b.failure(errRedisDown)
+
+	// Open: no calls until the cooldown has passed
+	if b.allow() {
+		t.Fatal("open breaker allowed a call during the cooldown")
+	}
+
+	// Half-open: a single probe is let through
+	b.openUntil = time.Now().Add(-time.Second)
+	if !b.allow() {
+		t.Fatal("breaker didn't probe after the cooldown")
+	}
+	if b.allow() {
+		t.Fatal("breaker allowed a second probe at once")
+	}
+
+	// A failed probe opens the breaker for another cooldown
+	b.failure(errRedisDown)
+	if b.allow() {
+		t.Fatal("breaker allowed a call after a failed probe")
+	}
+
+	// A successful probe closes it
+	b.openUntil = time.Now().Add(-time.Second)
+	if !b.allow() {
+		t.Fatal("breaker didn't probe after the second cooldown")
+	}
+	b.success()
+	for i := 0; i < 3; i++ {
+		if !b.allow() {
+			t.Fatalf("call %d refused after recovery", i)
+		}
+	}
+}
+
+func TestCircuitBreakerReleasedProbe(t *testing.T) {
+	b := newCircuitBreaker(1, time.Minute)
+	b.failure(errRedisDown)
+	b.openUntil = time.Now().Add(-time.Second)
+
+	if !b.allow() {
+		t.Fatal("breaker didn't probe after the cooldown")
+	}
+	b.release()
+
+	if !b.allow() {
+		t.Fatal("a released probe left the breaker stuck open")
+	}
+}
+
+// deadRedis returns a client for an address nothing listens on
+func deadRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRateLimiterCancelledProbe(t *testing.T) {
+	rl := NewRateLimiter(deadRedis(t), RateLimiterConfig{Enabled: true, BreakerThreshold: 1})
+	policy := RateLimitPolicy{Name: "read", MaxRequests: 10, Window: time.Minute}
+
+	if _, ok := rl.check(context.Background(), policy, "ip:192.0.2.1"); ok {
+		t.Fatal("check succeeded against a dead Redis")
+	}
+	rl.breaker.openUntil = time.Now().Add(-time.Second)
+
+	// The probe's client goes away before Redis answers
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ok := rl.check(ctx, policy, "ip:192.0.2.1"); ok {
+		t.Fatal("cancelled check succeeded")
+	}
+
+	if !rl.breaker.allow() {
+		t.Error("breaker stuck open after a cancelled probe")
+	}
+}
+
+func TestRateLimiterFailureModes(t *testing.T) {
+	policy := RateLimitPolicy{Name: "read", MaxRequests: 2, Window: time.Minute}
+	tests := []struct {
+		mode RateLimitFailureMode
+		want []int
+	}{
+		{RateLimitFailLocal, []int{200, 200, 429}},
+		{RateLimitFailOpen, []int{200, 200, 200}},
+		{RateLimitFailClosed, []int{503, 503, 503}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			rl := NewRateLimiter(deadRedis(t), RateLimiterConfig{Enabled: true, FailureMode: tt.mode})
+			handler := rl.Limit(policy, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			for i, want := range tt.want {
+				r := httptest.NewRequest(http.MethodGet, "/api/movies", nil)
+				r.RemoteAddr = "192.0.2.1:1234"
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+				if w.Code != want {
+					t.Errorf("request %d: status = %d, want %d", i, w.Code, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalLimiterRefills(t *testing.T) {
+	l := newLocalLimiter()
+	policy := RateLimitPolicy{Name: "read", MaxRequests: 2, Window: time.Minute}
+
+	for i := 0; i < 2; i++ {
+		if !l.take("read:ip:192.0.2.1", policy).allowed {
+			t.Fatalf("request %d rejected within the budget", i)
+		}
+	}
+	result := l.take("read:ip:192.0.2.1", policy)
+	if result.allowed || result.remaining != 0 {
+		t.Fatalf("over budget: got %+v, want rejected with nothing remaining", result)
+	}
+	if result.reset <= 0 || result.reset > 30*time.Second {
+		t.Errorf("reset = %v, want the time until the next token", result.reset)
+	}
+	if !l.take("read:ip:192.0.2.2", policy).allowed {
+		t.Error("another client shared the exhausted bucket")
+	}
+
+	// Half the window refills half the bucket
+	l.buckets["read:ip:192.0.2.1"].last = time.Now().Add(-30 * time.Second)
+	if !l.take("read:ip:192.0.2.1", policy).allowed {
+		t.Error("bucket didn't refill over time")
+	}
+}
@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liamwears/reelscore/internal/apperror"
+	"github.com/liamwears/reelscore/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// testRedis connects to the Redis at REDIS_ADDR, skipping the test when it is unset
+func testRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR not set, skipping Redis test")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("failed to connect to Redis at %s: %v", addr, err)
+	}
+	return client
+}
+
+func TestRateLimiterIdentifier(t *testing.T) {
+	rl := NewRateLimiter(nil, RateLimiterConfig{})
+	token := &models.APIToken{ID: uuid.New()}
+	userID := uuid.New()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"unverified bearer", context.Background(), "ip:192.0.2.1"},
+		{"token", context.WithValue(context.Background(), APITokenContextKey, token), "token:" + token.ID.String()},
+		{"session", context.WithValue(context.Background(), UserIDContextKey, userID), "user:" + userID.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/movies", nil).WithContext(tt.ctx)
+			r.RemoteAddr = "192.0.2.1:1234"
+			r.Header.Set("Authorization", "Bearer "+uuid.NewString())
+
+			if got := rl.getIdentifier(r); got != tt.want {
+				t.Errorf("getIdentifier() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterDoesNotRecordRejectedRequests(t *testing.T) {
+	client := testRedis(t)
+	rl := NewRateLimiter(client, RateLimiterConfig{Enabled: true})
+	policy := RateLimitPolicy{Name: "test-" + uuid.NewString(), MaxRequests: 2, Window: time.Minute}
+	key := "ratelimit:" + policy.Name + ":ip:192.0.2.1"
+	t.Cleanup(func() { client.Del(context.Background(), key) })
+
+	handler := rl.Limit(policy, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	var codes []int
+	for range 5 {
+		r := httptest.NewRequest(http.MethodGet, "/api/movies", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+
+	want := []int{200, 200, 429, 429, 429}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("status codes = %v, want %v", codes, want)
+		}
+	}
+	if n := client.ZCard(context.Background(), key).Val(); n != 2 {
+		t.Errorf("window holds %d requests, want only the 2 admitted", n)
+	}
+}
+
+// serveLimited sends a GET from addr through handler
+func serveLimited(handler http.Handler, addr string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/movies", nil)
+	r.RemoteAddr = addr + ":1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// testPolicy returns a policy no other test run shares and deletes its windows when
+// the test ends
+func testPolicy(t *testing.T, client *redis.Client, maxRequests int) RateLimitPolicy {
+	t.Helper()
+
+	policy := RateLimitPolicy{Name: "test-" + uuid.NewString(), MaxRequests: maxRequests, Window: time.Minute}
+	t.Cleanup(func() {
+		keys, _ := client.Keys(context.Background(), "ratelimit:"+policy.Name+":*").Result()
+		if len(keys) > 0 {
+			client.Del(context.Background(), keys...)
+		}
+	})
+	return policy
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	backends := []struct {
+		name  string
+		redis func(t *testing.T) *redis.Client
+	}{
+		{"redis", testRedis},
+		{"local fallback", deadRedis},
+	}
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			client := backend.redis(t)
+			rl := NewRateLimiter(client, RateLimiterConfig{Enabled: true})
+			policy := testPolicy(t, client, 3)
+			called := 0
+			handler := rl.Limit(policy, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called++ }))
+
+			for i, remaining := range []string{"2", "1", "0"} {
+				w := serveLimited(handler, "192.0.2.1")
+				if w.Code != http.StatusOK {
+					t.Fatalf("request %d: status = %d, want 200", i, w.Code)
+				}
+				h := w.Header()
+				if got := h.Get("RateLimit-Policy"); got != "3;w=60" {
+					t.Errorf("request %d: RateLimit-Policy = %q, want 3;w=60", i, got)
+				}
+				if got := h.Get("RateLimit-Limit"); got != "3" {
+					t.Errorf("request %d: RateLimit-Limit = %q, want 3", i, got)
+				}
+				if got := h.Get("RateLimit-Remaining"); got != remaining {
+					t.Errorf("request %d: RateLimit-Remaining = %q, want %s", i, got, remaining)
+				}
+				if reset, err := strconv.Atoi(h.Get("RateLimit-Reset")); err != nil || reset < 1 || reset > 60 {
+					t.Errorf("request %d: RateLimit-Reset = %q, want 1-60 seconds", i, h.Get("RateLimit-Reset"))
+				}
+				if h.Get("Retry-After") != "" {
+					t.Errorf("request %d: Retry-After set on an admitted request", i)
+				}
+			}
+
+			w := serveLimited(handler, "192.0.2.1")
+			if w.Code != http.StatusTooManyRequests {
+				t.Fatalf("over budget: status = %d, want 429", w.Code)
+			}
+			if called != 3 {
+				t.Errorf("handler called %d times, want 3", called)
+			}
+			h := w.Header()
+			if got := h.Get("RateLimit-Remaining"); got != "0" {
+				t.Errorf("over budget: RateLimit-Remaining = %q, want 0", got)
+			}
+			retryAfter, err := strconv.Atoi(h.Get("Retry-After"))
+			if err != nil || retryAfter < 1 || retryAfter > 60 {
+				t.Errorf("Retry-After = %q, want 1-60 seconds", h.Get("Retry-After"))
+			}
+			if h.Get("Retry-After") != h.Get("RateLimit-Reset") {
+				t.Errorf("Retry-After = %q, RateLimit-Reset = %q, want them equal", h.Get("Retry-After"), h.Get("RateLimit-Reset"))
+			}
+
+			if ct := h.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			var body apperror.Response
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("decode 429 body: %v", err)
+			}
+			if body.Error.Code != apperror.CodeRateLimited || body.Error.Message != "Too many requests. Please try again later." {
+				t.Errorf("429 body = %+v, want the rate_limited error", body.Error)
+			}
+		})
+	}
+}
+
+func TestRateLimiterPoliciesCountSeparately(t *testing.T) {
+	client := testRedis(t)
+	rl := NewRateLimiter(client, RateLimiterConfig{Enabled: true})
+	read := rl.Limit(testPolicy(t, client, 1), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	write := rl.Limit(testPolicy(t, client, 1), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	steps := []struct {
+		name    string
+		handler http.Handler
+		addr    string
+		want    int
+	}{
+		{"first read", read, "192.0.2.1", http.StatusOK},
+		{"read over budget", read, "192.0.2.1", http.StatusTooManyRequests},
+		{"write has its own budget", write, "192.0.2.1", http.StatusOK},
+		{"write over budget", write, "192.0.2.1", http.StatusTooManyRequests},
+		{"another client has its own budget", read, "192.0.2.2", http.StatusOK},
+	}
+	for _, step := range steps {
+		if w := serveLimited(step.handler, step.addr); w.Code != step.want {
+			t.Errorf("%s: status = %d, want %d", step.name, w.Code, step.want)
+		}
+	}
+}
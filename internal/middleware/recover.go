@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/liamwears/reelscore/internal/apperror"
+	"github.com/liamwears/reelscore/internal/logging"
+)
+
+// errorPage is shown to browsers when a page handler panics. It is self-contained as
+// the middleware can't reach the page templates.
+var errorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en" data-theme="reelscore">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Something went wrong - ReelScore</title>
+    <link href="/static/css/output.css" rel="stylesheet">
+</head>
+<body class="min-h-screen flex items-center justify-center">
+    <div class="card bg-base-100 shadow-xl max-w-md">
+        <div class="card-body">
+            <h1 class="card-title">Something went wrong</h1>
+            <p>We couldn't load this page. Please try again in a moment.</p>
+            {{with .}}<p class="text-sm opacity-60">Request ID: <code>{{.}}</code></p>{{end}}
+            <div class="card-actions justify-end">
+                <a href="/movies" class="btn btn-primary">Back to ReelScore</a>
+            </div>
+        </div>
+    </div>
+</body>
+</html>
+`))
+
+// Recover turns a panic in a handler into a 500 response, logging the panic with its
+// stack. API and HTMX requests get JSON, browsers get an error page. Place it inside
+// Logger so the log record carries the request ID and the 500 is logged.
+func Recover(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// Handlers abort responses on purpose with this, net/http handles it
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				logger.ErrorContext(r.Context(), "Panic while handling request",
+					"panic", rec,
+					"stack", string(debug.Stack()),
+				)
+
+				// Too late to replace a response that has started
+				if wrapped.written {
+					return
+				}
+				writePanicResponse(wrapped, r)
+			}()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
+}
+
+// writePanicResponse writes the 500 response, dropping headers the handler set for
+// the response it didn't finish
+func writePanicResponse(w http.ResponseWriter, r *http.Request) {
+	for _, header := range []string{"Content-Disposition", "Content-Length", "HX-Redirect", "HX-Trigger"} {
+		w.Header().Del(header)
+	}
+	if wantsJSON(r) {
+		apperror.Write(w, r, apperror.Internal("Internal server error"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	errorPage.Execute(w, logging.RequestID(r.Context()))
+}
+
+// wantsJSON reports whether a request came from the API, HTMX or a client asking for JSON
+func wantsJSON(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/") ||
+		r.Header.Get("HX-Request") == "true" ||
+		strings.Contains(r.Header.Get("Accept"), "application/json")
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/liamwears/reelscore/internal/apperror"
+)
+
+func TestRecoverRespondsByAccept(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		accept      string
+		contentType string
+	}{
+		{"browser", "/movies", "text/html,application/xhtml+xml", "text/html; charset=utf-8"},
+		{"json client", "/movies", "application/json", "application/json"},
+		{"api", "/api/movies", "*/*", "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			handler := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("HX-Trigger", `{"showMessage":"Movie deleted"}`)
+				panic("boom")
+			}))
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want 500", w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if got := w.Header().Get("HX-Trigger"); got != "" {
+				t.Errorf("HX-Trigger = %q, want it dropped", got)
+			}
+
+			if tt.contentType == "application/json" {
+				var resp apperror.Response
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("decode body: %v", err)
+				}
+				if resp.Error.Code != apperror.CodeInternal {
+					t.Errorf("error code = %q, want %q", resp.Error.Code, apperror.CodeInternal)
+				}
+			} else if !strings.Contains(w.Body.String(), "Something went wrong") {
+				t.Errorf("body = %q, want the error page", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRecoverLeavesStartedResponses(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "partial")
+		panic("boom")
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/movies", nil))
+
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("got %d %q, want the started response left alone", w.Code, w.Body.String())
+	}
+}
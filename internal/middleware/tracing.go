@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace starts a server span for each request, continuing the caller's trace when it
+// sends a traceparent header. The span is renamed after the route once it is known.
+func Trace(next http.Handler) http.Handler {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// otelhttp trusts X-Forwarded-For as is, the resolved address is what we log
+		trace.SpanFromContext(r.Context()).SetAttributes(semconv.ClientAddress(ClientIP(r)))
+		next.ServeHTTP(w, r)
+	})
+	return otelhttp.NewHandler(handler, "request",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return spanName(r)
+		}),
+	)
+}
+
+// traceRoute names the request's span after the matched route pattern, like
+// "GET /api/movies/{id}", so spans of the same route group together
+func traceRoute(r *http.Request) {
+	span := trace.SpanFromContext(r.Context())
+	if !span.IsRecording() || r.Pattern == "" {
+		return
+	}
+	span.SetName(spanName(r))
+	span.SetAttributes(semconv.HTTPRoute(route(r)))
+}
+
+// spanName names a request's span after its method and, once routed, its route
+func spanName(r *http.Request) string {
+	if r.Pattern == "" {
+		return r.Method
+	}
+	return r.Method + " " + route(r)
+}
+
+// route returns the path of the route pattern the request matched
+func route(r *http.Request) string {
+	if method, path, ok := strings.Cut(r.Pattern, " "); ok && method != "" {
+		return path
+	}
+	return r.Pattern
+}
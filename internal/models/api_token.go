@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenScope is a permission granted to a personal access token
+type TokenScope string
+
+const (
+	// ScopeLibraryRead allows reading the library and looking up TMDB data
+	ScopeLibraryRead TokenScope = "library:read"
+	// ScopeLibraryWrite allows adding, updating and removing library items
+	ScopeLibraryWrite TokenScope = "library:write"
+)
+
+// IsValid checks if the token scope is valid
+func (s TokenScope) IsValid() bool {
+	return s == ScopeLibraryRead || s == ScopeLibraryWrite
+}
+
+// APIToken represents a personal access token for the JSON API
+type APIToken struct {
+	ID         uuid.UUID    `db:"id" json:"id"`
+	UserID     uuid.UUID    `db:"userId" json:"userId"`
+	Name       string       `db:"name" json:"name"`
+	Prefix     string       `db:"prefix" json:"prefix"`
+	Scopes     []TokenScope `db:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time   `db:"expiresAt" json:"expiresAt"`
+	LastUsedAt *time.Time   `db:"lastUsedAt" json:"lastUsedAt"`
+	RevokedAt  *time.Time   `db:"revokedAt" json:"revokedAt"`
+	CreatedAt  time.Time    `db:"createdAt" json:"createdAt"`
+}
+
+// HasScope checks if the token was granted a scope
+func (t APIToken) HasScope(scope TokenScope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the token can still be used
+func (t APIToken) Active() bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || t.ExpiresAt.After(time.Now()))
+}
+
+// CreateAPITokenInput represents the input for creating a personal access token
+type CreateAPITokenInput struct {
+	Name   string       `json:"name" validate:"required,max=100"`
+	Scopes []TokenScope `json:"scopes" validate:"required"`
+	// ExpiresInDays is how long the token lasts, nil or 0 never expires
+	ExpiresInDays *int `json:"expiresInDays,omitempty" validate:"omitempty,min=0,max=365"`
+}
+
+// CreatedAPIToken is a newly created token together with its secret, which is
+// only ever returned once
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
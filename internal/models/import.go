@@ -0,0 +1,47 @@
+package models
+
+// ImportStatus describes how an imported row was resolved
+type ImportStatus string
+
+const (
+	// ImportStatusMatched means the row was resolved to a single TMDB movie and imported
+	ImportStatusMatched ImportStatus = "matched"
+	// ImportStatusExisting means the row matched a movie already in the library
+	ImportStatusExisting ImportStatus = "existing"
+	// ImportStatusAmbiguous means several TMDB movies matched the row
+	ImportStatusAmbiguous ImportStatus = "ambiguous"
+	// ImportStatusUnmatched means no TMDB movie matched the row
+	ImportStatusUnmatched ImportStatus = "unmatched"
+	// ImportStatusFailed means the row could not be looked up or inserted
+	ImportStatusFailed ImportStatus = "failed"
+)
+
+// ImportCandidate represents a TMDB movie a row could resolve to
+type ImportCandidate struct {
+	TmdbID      int    `json:"tmdbId"`
+	Title       string `json:"title"`
+	ReleaseDate string `json:"releaseDate"`
+}
+
+// ImportRow represents the outcome of importing one film
+type ImportRow struct {
+	Name       string            `json:"name"`
+	Year       string            `json:"year"`
+	Status     ImportStatus      `json:"status"`
+	TmdbID     int               `json:"tmdbId,omitempty"`
+	Watched    bool              `json:"watched"`
+	Score      *float64          `json:"score,omitempty"`
+	Viewings   int               `json:"viewings"`
+	Candidates []ImportCandidate `json:"candidates,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// ImportReport summarises an import
+type ImportReport struct {
+	Matched   int         `json:"matched"`
+	Existing  int         `json:"existing"`
+	Ambiguous int         `json:"ambiguous"`
+	Unmatched int         `json:"unmatched"`
+	Failed    int         `json:"failed"`
+	Rows      []ImportRow `json:"rows"`
+}
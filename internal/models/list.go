@@ -0,0 +1,53 @@
+package models
+
+// SortKey is an allow-listed library sort key
+type SortKey string
+
+const (
+	// SortByTmdbScore sorts by TMDB score, the default
+	SortByTmdbScore SortKey = "tmdbScore"
+	// SortByScore sorts by the user's own score
+	SortByScore SortKey = "score"
+	// SortByReleased sorts by release date for movies and first-aired date for series
+	SortByReleased SortKey = "released"
+	// SortByAdded sorts by when the item was added to the library
+	SortByAdded SortKey = "added"
+	// SortByTitle sorts alphabetically by title
+	SortByTitle SortKey = "title"
+)
+
+// IsValid checks if the sort key is valid
+func (k SortKey) IsValid() bool {
+	switch k {
+	case SortByTmdbScore, SortByScore, SortByReleased, SortByAdded, SortByTitle:
+		return true
+	}
+	return false
+}
+
+// SortOrder is a sort direction
+type SortOrder string
+
+const (
+	// SortAsc sorts in ascending order
+	SortAsc SortOrder = "asc"
+	// SortDesc sorts in descending order
+	SortDesc SortOrder = "desc"
+)
+
+// IsValid checks if the sort order is valid
+func (o SortOrder) IsValid() bool {
+	return o == SortAsc || o == SortDesc
+}
+
+// ListOptions holds the sort and filter options shared by library listings
+type ListOptions struct {
+	Sort     SortKey   `query:"sort"`
+	Order    SortOrder `query:"order"`
+	MinScore *float64  `query:"minScore" validate:"omitempty,min=0,max=10"`
+	MaxScore *float64  `query:"maxScore" validate:"omitempty,min=0,max=10"`
+	MinYear  *int      `query:"minYear"`
+	MaxYear  *int      `query:"maxYear"`
+	// Unrated only returns items the user has not scored yet
+	Unrated bool `query:"unrated"`
+}
@@ -41,6 +41,7 @@ type UpdateMovieInput struct {
 
 // ListMoviesInput represents the input for listing movies
 type ListMoviesInput struct {
+	ListOptions
 	Watched bool   `query:"watched"`
 	Query   string `query:"query"`
 	Page    int    `query:"page" validate:"min=1"`
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProgressScope determines which episodes a progress update applies to
+type ProgressScope string
+
+const (
+	// ProgressScopeEpisode marks a single episode
+	ProgressScopeEpisode ProgressScope = "episode"
+	// ProgressScopeSeason marks every aired episode of a season
+	ProgressScopeSeason ProgressScope = "season"
+	// ProgressScopeUpTo marks every aired episode up to and including the given one
+	ProgressScopeUpTo ProgressScope = "upTo"
+)
+
+// IsValid checks if the scope is valid
+func (s ProgressScope) IsValid() bool {
+	return s == ProgressScopeEpisode || s == ProgressScopeSeason || s == ProgressScopeUpTo
+}
+
+// EpisodeProgress represents a watched episode of a serie
+type EpisodeProgress struct {
+	ID            uuid.UUID `db:"id" json:"id"`
+	SerieID       uuid.UUID `db:"serieId" json:"serieId"`
+	UserID        uuid.UUID `db:"userId" json:"userId"`
+	SeasonNumber  int       `db:"seasonNumber" json:"seasonNumber"`
+	EpisodeNumber int       `db:"episodeNumber" json:"episodeNumber"`
+	WatchedAt     time.Time `db:"watchedAt" json:"watchedAt"`
+}
+
+// MarkProgressInput represents the input for marking episodes as watched
+type MarkProgressInput struct {
+	Scope   ProgressScope `json:"scope" validate:"required"`
+	Season  int           `json:"season" validate:"min=1"`
+	Episode int           `json:"episode,omitempty"`
+}
+
+// SeasonProgress represents watch progress for a single season
+type SeasonProgress struct {
+	SeasonNumber    int   `json:"seasonNumber"`
+	AiredEpisodes   int   `json:"airedEpisodes"`
+	WatchedEpisodes []int `json:"watchedEpisodes"`
+	Complete        bool  `json:"complete"`
+}
+
+// SerieProgress represents episode-level watch progress for a serie
+type SerieProgress struct {
+	SerieID         uuid.UUID        `json:"serieId"`
+	TmdbID          int              `json:"tmdbId"`
+	WatchedEpisodes int              `json:"watchedEpisodes"`
+	AiredEpisodes   int              `json:"airedEpisodes"`
+	Percent         float64          `json:"percent"`
+	Seasons         []SeasonProgress `json:"seasons"`
+	// NextEpisode is the first aired episode not yet watched, if any
+	NextEpisode *EpisodeRef `json:"nextEpisode"`
+	Watched     bool        `json:"watched"`
+	// MarkedWatched is set when the update that returned this progress flipped the
+	// serie to watched
+	MarkedWatched bool `json:"-"`
+}
+
+// EpisodeRef identifies an episode by season and episode number
+type EpisodeRef struct {
+	SeasonNumber  int `json:"seasonNumber"`
+	EpisodeNumber int `json:"episodeNumber"`
+}
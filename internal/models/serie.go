@@ -41,6 +41,7 @@ type UpdateSerieInput struct {
 
 // ListSeriesInput represents the input for listing series
 type ListSeriesInput struct {
+	ListOptions
 	Watched bool   `query:"watched"`
 	Query   string `query:"query"`
 	Page    int    `query:"page" validate:"min=1"`
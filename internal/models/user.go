@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,17 +13,40 @@ type Provider string
 const (
 	ProviderGitHub Provider = "GITHUB"
 	ProviderGoogle Provider = "GOOGLE"
+	// ProviderOIDC covers every configured OpenID Connect provider
+	ProviderOIDC Provider = "OIDC"
 )
 
 // User represents a user in the system
 type User struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	Email     string    `db:"email" json:"email"`
+	Name      string    `db:"name" json:"name"`
+	CreatedAt time.Time `db:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `db:"updatedAt" json:"updatedAt"`
+	// DeletionScheduledAt is when the account will be deleted, if the user asked for it
+	DeletionScheduledAt *time.Time `db:"deletionScheduledAt" json:"deletionScheduledAt,omitempty"`
+}
+
+// UserIdentity represents a login provider account linked to a user
+type UserIdentity struct {
 	ID         uuid.UUID `db:"id" json:"id"`
-	ProviderID string    `db:"providerId" json:"providerId"`
+	UserID     uuid.UUID `db:"userId" json:"userId"`
 	Provider   Provider  `db:"provider" json:"provider"`
+	ProviderID string    `db:"providerId" json:"providerId"`
 	Email      string    `db:"email" json:"email"`
-	Name       string    `db:"name" json:"name"`
 	CreatedAt  time.Time `db:"createdAt" json:"createdAt"`
-	UpdatedAt  time.Time `db:"updatedAt" json:"updatedAt"`
+}
+
+// ProviderName returns the name the identity's provider is known by: "GITHUB",
+// "GOOGLE" or the configured name of an OpenID Connect provider
+func (i UserIdentity) ProviderName() string {
+	if i.Provider == ProviderOIDC {
+		if name, _, ok := strings.Cut(i.ProviderID, ":"); ok {
+			return name
+		}
+	}
+	return i.Provider.String()
 }
 
 // String returns the string representation of Provider
@@ -32,5 +56,11 @@ func (p Provider) String() string {
 
 // IsValid checks if the provider is valid
 func (p Provider) IsValid() bool {
-	return p == ProviderGitHub || p == ProviderGoogle
+	return p == ProviderGitHub || p == ProviderGoogle || p == ProviderOIDC
+}
+
+// OIDCProviderID namespaces an OpenID Connect subject by provider name, as subjects
+// are only unique per issuer
+func OIDCProviderID(name, subject string) string {
+	return name + ":" + subject
 }
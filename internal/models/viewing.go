@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Viewing represents a dated viewing of a movie in the user's diary
+type Viewing struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	MovieID   uuid.UUID `db:"movieId" json:"movieId"`
+	UserID    uuid.UUID `db:"userId" json:"userId"`
+	WatchedOn time.Time `db:"watchedOn" json:"watchedOn"`
+	Rating    *float64  `db:"rating" json:"rating"`
+	Note      *string   `db:"note" json:"note"`
+	Rewatch   bool      `db:"rewatch" json:"rewatch"`
+	CreatedAt time.Time `db:"createdAt" json:"createdAt"`
+}
+
+// CreateViewingInput represents the input for logging a viewing
+type CreateViewingInput struct {
+	// WatchedOn is a YYYY-MM-DD date, defaults to today
+	WatchedOn *string  `json:"watchedOn"`
+	Rating    *float64 `json:"rating,omitempty" validate:"omitempty,min=0,max=10"`
+	Note      *string  `json:"note,omitempty"`
+	// Rewatch defaults to whether the movie already has an earlier viewing
+	Rewatch *bool `json:"rewatch,omitempty"`
+}
+
+// DiaryEntry represents a viewing together with the movie it belongs to
+type DiaryEntry struct {
+	Viewing
+	TmdbID      int        `json:"tmdbId"`
+	Title       string     `json:"title"`
+	PosterPath  *string    `json:"posterPath"`
+	ReleaseDate *time.Time `json:"releaseDate"`
+}
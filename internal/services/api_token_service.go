@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/liamwears/reelscore/internal/models"
+)
+
+// apiTokenPrefix marks ReelScore tokens so they are easy to recognise in secret scanners
+const apiTokenPrefix = "rs_"
+
+// lastUsedResolution limits how often a token's last-used time is written
+const lastUsedResolution = time.Minute
+
+var (
+	// ErrInvalidAPIToken is returned when a token is unknown, revoked or expired
+	ErrInvalidAPIToken = errors.New("invalid api token")
+	// ErrInvalidAPITokenInput is returned when a token's name, scopes or expiry are invalid
+	ErrInvalidAPITokenInput = errors.New("invalid api token input")
+)
+
+// APITokenService manages personal access tokens
+type APITokenService struct {
+	db *pgxpool.Pool
+}
+
+// NewAPITokenService creates a new APITokenService
+func NewAPITokenService(db *pgxpool.Pool) *APITokenService {
+	return &APITokenService{db: db}
+}
+
+// HashAPIToken returns the hex SHA-256 of a token, which is all we store
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create creates a token and returns it with its secret
+func (s *APITokenService) Create(ctx context.Context, userID uuid.UUID, input models.CreateAPITokenInput) (*models.CreatedAPIToken, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		return nil, fmt.Errorf("%w: name must be 1 to 100 characters", ErrInvalidAPITokenInput)
+	}
+	if len(input.Scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPITokenInput)
+	}
+	scopes := make([]string, 0, len(input.Scopes))
+	for _, scope := range input.Scopes {
+		if !scope.IsValid() {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPITokenInput, scope)
+		}
+		scopes = append(scopes, string(scope))
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresInDays != nil && *input.ExpiresInDays != 0 {
+		if *input.ExpiresInDays < 0 || *input.ExpiresInDays > 365 {
+			return nil, fmt.Errorf("%w: expiry must be between 1 and 365 days", ErrInvalidAPITokenInput)
+		}
+		t := time.Now().AddDate(0, 0, *input.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	query := `
+		INSERT INTO "ApiToken" ("userId", name, "tokenHash", prefix, scopes, "expiresAt")
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, "userId", name, prefix, scopes, "expiresAt", "lastUsedAt", "revokedAt", "createdAt"
+	`
+
+	token, err := scanAPIToken(s.db.QueryRow(ctx, query, userID, name, HashAPIToken(secret), secret[:len(apiTokenPrefix)+6], scopes, expiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api token: %w", err)
+	}
+
+	return &models.CreatedAPIToken{APIToken: *token, Token: secret}, nil
+}
+
+// List lists a user's tokens, newest first, including revoked and expired ones
+func (s *APITokenService) List(ctx context.Context, userID uuid.UUID) ([]models.APIToken, error) {
+	query := `
+		SELECT id, "userId", name, prefix, scopes, "expiresAt", "lastUsedAt", "revokedAt", "createdAt"
+		FROM "ApiToken"
+		WHERE "userId" = $1
+		ORDER BY "createdAt" DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api token: %w", err)
+		}
+		tokens = append(tokens, *token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating api tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Revoke revokes one of a user's tokens
+func (s *APITokenService) Revoke(ctx context.Context, userID, tokenID uuid.UUID) error {
+	result, err := s.db.Exec(ctx, `
+		UPDATE "ApiToken" SET "revokedAt" = NOW()
+		WHERE id = $1 AND "userId" = $2 AND "revokedAt" IS NULL
+	`, tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api token: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// Authenticate looks up an active token by its secret and records that it was used
+func (s *APITokenService) Authenticate(ctx context.Context, secret string) (*models.APIToken, error) {
+	if !strings.HasPrefix(secret, apiTokenPrefix) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	query := `
+		SELECT id, "userId", name, prefix, scopes, "expiresAt", "lastUsedAt", "revokedAt", "createdAt"
+		FROM "ApiToken"
+		WHERE "tokenHash" = $1
+	`
+
+	token, err := scanAPIToken(s.db.QueryRow(ctx, query, HashAPIToken(secret)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidAPIToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api token: %w", err)
+	}
+	if !token.Active() {
+		return nil, ErrInvalidAPIToken
+	}
+
+	// Only write last-used once per resolution so busy scripts don't write on every call
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		if _, err := s.db.Exec(ctx, `UPDATE "ApiToken" SET "lastUsedAt" = $2 WHERE id = $1`, token.ID, now); err != nil {
+			return nil, fmt.Errorf("failed to record api token use: %w", err)
+		}
+		token.LastUsedAt = &now
+	}
+
+	return token, nil
+}
+
+// scanAPIToken scans a token row
+func scanAPIToken(row pgx.Row) (*models.APIToken, error) {
+	var token models.APIToken
+	var scopes []string
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.Prefix,
+		&scopes,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, scope := range scopes {
+		token.Scopes = append(token.Scopes, models.TokenScope(scope))
+	}
+
+	return &token, nil
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/liamwears/reelscore/internal/models"
+)
+
+// ExportFormat is a library export format
+type ExportFormat string
+
+const (
+	// ExportFormatCSV exports movies and series as one CSV
+	ExportFormatCSV ExportFormat = "csv"
+	// ExportFormatJSON exports movies and series as a JSON document
+	ExportFormatJSON ExportFormat = "json"
+	// ExportFormatLetterboxd exports watched movies in Letterboxd's import CSV format
+	ExportFormatLetterboxd ExportFormat = "letterboxd"
+)
+
+// ErrUnsupportedExportFormat is returned for an unknown export format
+var ErrUnsupportedExportFormat = errors.New("unsupported export format")
+
+// IsValid checks if the export format is valid
+func (f ExportFormat) IsValid() bool {
+	return f == ExportFormatCSV || f == ExportFormatJSON || f == ExportFormatLetterboxd
+}
+
+// ContentType returns the MIME type of the export format
+func (f ExportFormat) ContentType() string {
+	if f == ExportFormatJSON {
+		return "application/json"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Extension returns the file extension of the export format
+func (f ExportFormat) Extension() string {
+	if f == ExportFormatJSON {
+		return "json"
+	}
+	return "csv"
+}
+
+// ExportService streams a user's library in portable formats
+type ExportService struct {
+	movieService *MovieService
+	serieService *SerieService
+}
+
+// NewExportService creates a new ExportService
+func NewExportService(movieService *MovieService, serieService *SerieService) *ExportService {
+	return &ExportService{
+		movieService: movieService,
+		serieService: serieService,
+	}
+}
+
+// Export writes the user's whole library to w, row by row
+func (s *ExportService) Export(ctx context.Context, userID uuid.UUID, format ExportFormat, w io.Writer) error {
+	switch format {
+	case ExportFormatCSV:
+		return s.exportCSV(ctx, userID, w)
+	case ExportFormatJSON:
+		return s.exportJSON(ctx, userID, w)
+	case ExportFormatLetterboxd:
+		return s.exportLetterboxd(ctx, userID, w)
+	default:
+		return ErrUnsupportedExportFormat
+	}
+}
+
+// Archive writes a zip of everything held about a user: library.json with the whole
+// library, plus one JSON file per entry of records (e.g. "profile" → profile.json)
+func (s *ExportService) Archive(ctx context.Context, userID uuid.UUID, records map[string]interface{}, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	library, err := zw.Create("library.json")
+	if err != nil {
+		return err
+	}
+	if err := s.exportJSON(ctx, userID, library); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(records))
+	for name := range records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f, err := zw.Create(name + ".json")
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(records[name]); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+// exportCSV writes movies and series as a single CSV with a type column
+func (s *ExportService) exportCSV(ctx context.Context, userID uuid.UUID, w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	cw.Write([]string{"type", "tmdbId", "title", "releaseDate", "tmdbScore", "score", "watched", "createdAt", "updatedAt"})
+
+	err := s.movieService.Each(ctx, userID, func(m models.Movie) error {
+		return cw.Write([]string{
+			"movie",
+			strconv.Itoa(m.TmdbID),
+			csvCell(m.Title),
+			formatDate(m.ReleaseDate),
+			formatScore(m.TmdbScore),
+			formatScore(m.Score),
+			strconv.FormatBool(m.Watched),
+			m.CreatedAt.Format(time.RFC3339),
+			m.UpdatedAt.Format(time.RFC3339),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.serieService.Each(ctx, userID, func(m models.Serie) error {
+		return cw.Write([]string{
+			"serie",
+			strconv.Itoa(m.TmdbID),
+			csvCell(m.Title),
+			formatDate(m.FirstAired),
+			formatScore(m.TmdbScore),
+			formatScore(m.Score),
+			strconv.FormatBool(m.Watched),
+			m.CreatedAt.Format(time.RFC3339),
+			m.UpdatedAt.Format(time.RFC3339),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportJSON writes {"movies":[...],"series":[...]} one element at a time
+func (s *ExportService) exportJSON(ctx context.Context, userID uuid.UUID, w io.Writer) error {
+	enc := json.NewEncoder(w)
+
+	// writeArray streams the elements produced by each into a JSON array
+	writeArray := func(each func(func(v interface{}) error) error) error {
+		first := true
+		return each(func(v interface{}) error {
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			return enc.Encode(v)
+		})
+	}
+
+	if _, err := fmt.Fprintf(w, `{"exportedAt":%q,"movies":[`, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	err := writeArray(func(write func(v interface{}) error) error {
+		return s.movieService.Each(ctx, userID, func(m models.Movie) error { return write(m) })
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, `],"series":[`); err != nil {
+		return err
+	}
+	err = writeArray(func(write func(v interface{}) error) error {
+		return s.serieService.Each(ctx, userID, func(m models.Serie) error { return write(m) })
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+// exportLetterboxd writes watched movies using Letterboxd's import columns. Series and
+// the watchlist are left out as Letterboxd cannot import them into the film log.
+func (s *ExportService) exportLetterboxd(ctx context.Context, userID uuid.UUID, w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	cw.Write([]string{"tmdbID", "Title", "Year", "Rating10"})
+
+	err := s.movieService.Each(ctx, userID, func(m models.Movie) error {
+		if !m.Watched {
+			return nil
+		}
+
+		year := ""
+		if m.ReleaseDate != nil {
+			year = strconv.Itoa(m.ReleaseDate.Year())
+		}
+		// Rating10 only accepts whole numbers from 1 to 10
+		rating := ""
+		if r := int(math.Round(m.Score)); r >= 1 {
+			rating = strconv.Itoa(r)
+		}
+
+		return cw.Write([]string{strconv.Itoa(m.TmdbID), csvCell(m.Title), year, rating})
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell neutralises text that spreadsheets would run as a formula, like a title of
+// "=HYPERLINK(...)", by prefixing it with a quote so it is shown as typed
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// formatDate formats an optional date as YYYY-MM-DD
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// formatScore formats a score without trailing zeros
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
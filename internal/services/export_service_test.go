@@ -0,0 +1,26 @@
+package services
+
+import "testing"
+
+func TestCSVCell(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"The Matrix", "The Matrix"},
+		{"", ""},
+		{`=HYPERLINK("https://evil.example.com","Click")`, `'=HYPERLINK("https://evil.example.com","Click")`},
+		{"+1+1", "'+1+1"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"\t=1+1", "'\t=1+1"},
+		{"\r=1+1", "'\r=1+1"},
+		{"Mission: Impossible - Fallout", "Mission: Impossible - Fallout"},
+		{"1+1=2", "1+1=2"},
+	}
+	for _, tt := range tests {
+		if got := csvCell(tt.value); got != tt.want {
+			t.Errorf("csvCell(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
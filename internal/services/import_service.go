@@ -0,0 +1,369 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/liamwears/reelscore/internal/apperror"
+	"github.com/liamwears/reelscore/internal/models"
+)
+
+var (
+	// ErrUnsupportedImport is returned when an upload is neither a Letterboxd zip nor CSV
+	ErrUnsupportedImport = errors.New("unsupported import file")
+	// ErrImportTooLarge is returned when a zip holds too many files or a file too big
+	// to be a Letterboxd export
+	ErrImportTooLarge = errors.New("import file too large")
+)
+
+const (
+	// maxImportZipEntries caps the number of files in an export zip
+	maxImportZipEntries = 10000
+	// maxImportFileSize caps the uncompressed size of each file read from a zip, so a
+	// small upload can't expand into gigabytes
+	maxImportFileSize = 64 << 20
+)
+
+// letterboxdFiles lists the export files we import, in the order they are applied
+var letterboxdFiles = []string{"watched.csv", "ratings.csv", "diary.csv", "watchlist.csv"}
+
+// ImportService imports libraries from other services
+type ImportService struct {
+	tmdbService    *TMDBService
+	movieService   *MovieService
+	viewingService *ViewingService
+}
+
+// NewImportService creates a new ImportService
+func NewImportService(tmdbService *TMDBService, movieService *MovieService, viewingService *ViewingService) *ImportService {
+	return &ImportService{
+		tmdbService:    tmdbService,
+		movieService:   movieService,
+		viewingService: viewingService,
+	}
+}
+
+// letterboxdFilm aggregates every row about one film across the export files
+type letterboxdFilm struct {
+	name      string
+	year      string
+	watched   bool
+	watchlist bool
+	rating    *float64
+	viewings  []models.CreateViewingInput
+}
+
+// ImportLetterboxd imports a Letterboxd export zip or one of its CSV files
+func (s *ImportService) ImportLetterboxd(ctx context.Context, userID uuid.UUID, filename string, data []byte) (*models.ImportReport, error) {
+	films, err := parseLetterboxd(filename, data)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ImportReport{Rows: []models.ImportRow{}}
+	for _, film := range films {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		row := s.importFilm(ctx, userID, film)
+		switch row.Status {
+		case models.ImportStatusMatched:
+			report.Matched++
+		case models.ImportStatusExisting:
+			report.Existing++
+		case models.ImportStatusAmbiguous:
+			report.Ambiguous++
+		case models.ImportStatusUnmatched:
+			report.Unmatched++
+		case models.ImportStatusFailed:
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	return report, nil
+}
+
+// importFilm resolves a film to a TMDB movie and adds it to the library
+func (s *ImportService) importFilm(ctx context.Context, userID uuid.UUID, film *letterboxdFilm) models.ImportRow {
+	row := models.ImportRow{
+		Name:    film.name,
+		Year:    film.year,
+		Watched: film.watched || !film.watchlist,
+	}
+	if film.rating != nil {
+		score := letterboxdScore(*film.rating)
+		row.Score = &score
+	}
+
+	match, candidates, err := s.resolve(ctx, film.name, film.year)
+	if err != nil {
+		row.Status = models.ImportStatusFailed
+		row.Error = err.Error()
+		return row
+	}
+	if match == nil {
+		row.Status = models.ImportStatusUnmatched
+		if len(candidates) > 0 {
+			row.Status = models.ImportStatusAmbiguous
+			for _, c := range candidates {
+				row.Candidates = append(row.Candidates, models.ImportCandidate{
+					TmdbID:      c.ID,
+					Title:       c.Title,
+					ReleaseDate: c.ReleaseDate,
+				})
+			}
+		}
+		return row
+	}
+	row.TmdbID = match.ID
+
+	releaseDate := match.ReleaseDate
+	movie, err := s.movieService.Create(ctx, userID, models.CreateMovieInput{
+		TmdbID:      match.ID,
+		Title:       match.Title,
+		PosterPath:  match.PosterPath,
+		ReleaseDate: &releaseDate,
+		Watched:     row.Watched,
+		TmdbScore:   match.VoteAverage,
+		Score:       row.Score,
+	})
+	if err != nil {
+		if errors.Is(err, apperror.ErrConflict) {
+			// Already in the library, leave it and its viewings untouched
+			row.Status = models.ImportStatusExisting
+			return row
+		}
+		row.Status = models.ImportStatusFailed
+		row.Error = err.Error()
+		return row
+	}
+
+	row.Status = models.ImportStatusMatched
+	for _, viewing := range film.viewings {
+		if _, err := s.viewingService.Create(ctx, userID, movie.ID, viewing); err != nil {
+			row.Error = fmt.Sprintf("failed to import viewing: %v", err)
+			continue
+		}
+		row.Viewings++
+	}
+
+	return row
+}
+
+// resolve searches TMDB for a film and matches it on release year. It returns the
+// match when exactly one movie fits, otherwise the candidates that did.
+func (s *ImportService) resolve(ctx context.Context, name, year string) (*TMDBMovie, []TMDBMovie, error) {
+	result, err := s.tmdbService.SearchMovies(ctx, name, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var candidates []TMDBMovie
+	for _, movie := range result.Results {
+		if year == "" || strings.HasPrefix(movie.ReleaseDate, year) {
+			candidates = append(candidates, movie)
+		}
+	}
+	if len(candidates) == 1 {
+		return &candidates[0], nil, nil
+	}
+
+	// Several movies share the year, an exact title match breaks the tie
+	var exact []TMDBMovie
+	for _, movie := range candidates {
+		if strings.EqualFold(movie.Title, name) {
+			exact = append(exact, movie)
+		}
+	}
+	if len(exact) == 1 {
+		return &exact[0], nil, nil
+	}
+
+	return nil, candidates, nil
+}
+
+// letterboxdScore converts Letterboxd's 0.5-5 star rating onto the 0-10 score range
+func letterboxdScore(stars float64) float64 {
+	return min(max(stars*2, 0), 10)
+}
+
+// readZipFile reads a file from a zip, refusing to inflate it past maxImportFileSize.
+// The size in the zip header is checked first but can't be trusted, so the read is
+// limited too.
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > maxImportFileSize {
+		return nil, fmt.Errorf("%w: %s is over %d MB", ErrImportTooLarge, f.Name, maxImportFileSize>>20)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, maxImportFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	if len(content) > maxImportFileSize {
+		return nil, fmt.Errorf("%w: %s is over %d MB", ErrImportTooLarge, f.Name, maxImportFileSize>>20)
+	}
+	return content, nil
+}
+
+// parseLetterboxd reads films from a Letterboxd export zip or a single CSV file
+func parseLetterboxd(filename string, data []byte) ([]*letterboxdFilm, error) {
+	files := make(map[string][]byte)
+
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedImport, err)
+		}
+		if len(zr.File) > maxImportZipEntries {
+			return nil, fmt.Errorf("%w: more than %d files", ErrImportTooLarge, maxImportZipEntries)
+		}
+		for _, f := range zr.File {
+			// Only the top-level files we import, the export also has deleted/ and
+			// likes/ folders
+			if !slices.Contains(letterboxdFiles, f.Name) {
+				continue
+			}
+			content, err := readZipFile(f)
+			if err != nil {
+				return nil, err
+			}
+			files[f.Name] = content
+		}
+	} else {
+		name := strings.ToLower(path.Base(filename))
+		if !strings.HasSuffix(name, ".csv") {
+			return nil, ErrUnsupportedImport
+		}
+		known := false
+		for _, f := range letterboxdFiles {
+			known = known || f == name
+		}
+		if !known {
+			name = "watched.csv"
+		}
+		files[name] = data
+	}
+
+	var films []*letterboxdFilm
+	byKey := make(map[string]*letterboxdFilm)
+
+	for _, name := range letterboxdFiles {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+
+		rows, err := readCSV(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		for _, r := range rows {
+			filmName := r["Name"]
+			if filmName == "" {
+				continue
+			}
+
+			key := strings.ToLower(filmName) + "|" + r["Year"]
+			film, ok := byKey[key]
+			if !ok {
+				film = &letterboxdFilm{name: filmName, year: r["Year"]}
+				byKey[key] = film
+				films = append(films, film)
+			}
+
+			rating, hasRating := parseRating(r["Rating"])
+
+			switch name {
+			case "watched.csv":
+				film.watched = true
+			case "ratings.csv":
+				film.watched = true
+				if hasRating {
+					film.rating = &rating
+				}
+			case "diary.csv":
+				film.watched = true
+				if hasRating && film.rating == nil {
+					film.rating = &rating
+				}
+				watchedOn := r["Watched Date"]
+				if watchedOn == "" {
+					watchedOn = r["Date"]
+				}
+				rewatch := strings.EqualFold(r["Rewatch"], "yes")
+				viewing := models.CreateViewingInput{
+					WatchedOn: &watchedOn,
+					Rewatch:   &rewatch,
+				}
+				if hasRating {
+					score := letterboxdScore(rating)
+					viewing.Rating = &score
+				}
+				film.viewings = append(film.viewings, viewing)
+			case "watchlist.csv":
+				film.watchlist = true
+			}
+		}
+	}
+
+	return films, nil
+}
+
+// readCSV reads a CSV file into rows keyed by header
+func readCSV(content []byte) ([]map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(column)] = strings.TrimSpace(record[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseRating parses a Letterboxd star rating
+func parseRating(value string) (float64, bool) {
+	if value == "" {
+		return 0, false
+	}
+	rating, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return rating, true
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// buildZip writes a zip with the given files, each streamed from its reader
+func buildZip(t *testing.T, files map[string]io.Reader) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.Copy(w, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseLetterboxdZip(t *testing.T) {
+	data := buildZip(t, map[string]io.Reader{
+		"watched.csv":       strings.NewReader("Date,Name,Year,Letterboxd URI\n2024-01-02,Heat,1995,https://boxd.it/x\n"),
+		"ratings.csv":       strings.NewReader("Date,Name,Year,Letterboxd URI,Rating\n2024-01-02,Heat,1995,https://boxd.it/x,4.5\n"),
+		"likes/films.csv":   strings.NewReader("Date,Name,Year\n2024-01-02,Alien,1979\n"),
+		"profile.csv":       strings.NewReader("Username\nsomeone\n"),
+		"deleted/diary.csv": strings.NewReader("Name,Year\nAlien,1979\n"),
+	})
+
+	films, err := parseLetterboxd("letterboxd.zip", data)
+	if err != nil {
+		t.Fatalf("parseLetterboxd: %v", err)
+	}
+	if len(films) != 1 || films[0].name != "Heat" || !films[0].watched || films[0].rating == nil {
+		t.Fatalf("films = %+v, want Heat watched and rated", films)
+	}
+}
+
+func TestParseLetterboxdZipTooManyEntries(t *testing.T) {
+	files := make(map[string]io.Reader, maxImportZipEntries+1)
+	for i := 0; i <= maxImportZipEntries; i++ {
+		files[fmt.Sprintf("lists/list-%d.csv", i)] = strings.NewReader("")
+	}
+
+	_, err := parseLetterboxd("letterboxd.zip", buildZip(t, files))
+	if !errors.Is(err, ErrImportTooLarge) {
+		t.Errorf("parseLetterboxd() error = %v, want ErrImportTooLarge", err)
+	}
+}
+
+func TestParseLetterboxdZipBomb(t *testing.T) {
+	// Zeros compress about a thousandfold, so the upload stays small
+	zeros := io.LimitReader(zeroReader{}, maxImportFileSize+1)
+	data := buildZip(t, map[string]io.Reader{"watched.csv": zeros})
+	if len(data) > maxImportFileSize/100 {
+		t.Fatalf("zip is %d bytes, expected it to compress well", len(data))
+	}
+
+	_, err := parseLetterboxd("letterboxd.zip", data)
+	if !errors.Is(err, ErrImportTooLarge) {
+		t.Errorf("parseLetterboxd() error = %v, want ErrImportTooLarge", err)
+	}
+}
+
+// zeroReader reads an endless stream of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/liamwears/reelscore/internal/models"
+)
+
+// eachPageSize is how many rows Each reads from the database at a time
+const eachPageSize = 500
+
+// sortColumns maps allow-listed sort keys to SQL expressions. SortByReleased is
+// resolved per table since movies and series name their date column differently.
+var sortColumns = map[models.SortKey]string{
+	models.SortByTmdbScore: `"tmdbScore"`,
+	models.SortByScore:     `score`,
+	models.SortByAdded:     `"createdAt"`,
+	models.SortByTitle:     `LOWER(title)`,
+}
+
+// applyListFilters appends the score, year and unrated filters to a WHERE clause.
+// Only placeholders are added to the query, values always travel as args.
+func applyListFilters(query string, args []interface{}, opts models.ListOptions, dateColumn string) (string, []interface{}) {
+	if opts.Unrated {
+		query += " AND score = 0"
+	}
+	if opts.MinScore != nil {
+		args = append(args, *opts.MinScore)
+		query += fmt.Sprintf(" AND score >= $%d", len(args))
+	}
+	if opts.MaxScore != nil {
+		args = append(args, *opts.MaxScore)
+		query += fmt.Sprintf(" AND score <= $%d", len(args))
+	}
+	if opts.MinYear != nil {
+		args = append(args, *opts.MinYear)
+		query += fmt.Sprintf(` AND EXTRACT(YEAR FROM "%s") >= $%d`, dateColumn, len(args))
+	}
+	if opts.MaxYear != nil {
+		args = append(args, *opts.MaxYear)
+		query += fmt.Sprintf(` AND EXTRACT(YEAR FROM "%s") <= $%d`, dateColumn, len(args))
+	}
+	return query, args
+}
+
+// orderByClause builds an ORDER BY clause from allow-listed sort keys, falling back
+// to TMDB score descending for anything unknown
+func orderByClause(opts models.ListOptions, dateColumn string) string {
+	column, ok := sortColumns[opts.Sort]
+	if opts.Sort == models.SortByReleased {
+		column, ok = fmt.Sprintf(`"%s"`, dateColumn), true
+	}
+	if !ok {
+		column = sortColumns[models.SortByTmdbScore]
+	}
+
+	direction := "DESC"
+	if opts.Order == models.SortAsc || (opts.Order == "" && opts.Sort == models.SortByTitle) {
+		direction = "ASC"
+	}
+
+	// Tie-break on id so pagination is stable
+	return fmt.Sprintf("ORDER BY %s %s NULLS LAST, id", column, direction)
+}
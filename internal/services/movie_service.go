@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/liamwears/reelscore/internal/apperror"
 	"github.com/liamwears/reelscore/internal/models"
 )
 
@@ -49,6 +50,10 @@ func (s *MovieService) List(ctx context.Context, userID uuid.UUID, input models.
 		args = append(args, "%"+input.Query+"%")
 	}
 
+	// Add sort-independent filters
+	baseQuery, args = applyListFilters(baseQuery, args, input.ListOptions, "releaseDate")
+	argCount = len(args)
+
 	// Count total
 	var total int
 	countQuery := "SELECT COUNT(*) " + baseQuery
@@ -61,8 +66,7 @@ func (s *MovieService) List(ctx context.Context, userID uuid.UUID, input models.
 	query := `
 		SELECT id, "tmdbId", "createdAt", "updatedAt", title, "posterPath",
 		       "releaseDate", "tmdbScore", score, watched, "userId"
-	` + baseQuery + `
-		ORDER BY "tmdbScore" DESC
+	` + baseQuery + orderByClause(input.ListOptions, "releaseDate") + `
 		LIMIT $` + fmt.Sprintf("%d", argCount+1) + ` OFFSET $` + fmt.Sprintf("%d", argCount+2)
 
 	args = append(args, input.Limit, offset)
@@ -109,6 +113,83 @@ func (s *MovieService) List(ctx context.Context, userID uuid.UUID, input models.
 	}, nil
 }
 
+// Each passes every movie in a user's library to fn, oldest first, without loading
+// the whole library into memory. Movies are read a page at a time and the connection
+// goes back to the pool before fn sees them, so a slow consumer, like a client
+// downloading an export, never holds one.
+func (s *MovieService) Each(ctx context.Context, userID uuid.UUID, fn func(models.Movie) error) error {
+	var after *models.Movie
+	for {
+		page, err := s.eachPage(ctx, userID, after)
+		if err != nil {
+			return err
+		}
+
+		for _, movie := range page {
+			if err := fn(movie); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < eachPageSize {
+			return nil
+		}
+		after = &page[len(page)-1]
+	}
+}
+
+// eachPage reads the page of movies following after, or the first page when after is nil
+func (s *MovieService) eachPage(ctx context.Context, userID uuid.UUID, after *models.Movie) ([]models.Movie, error) {
+	query := `
+		SELECT id, "tmdbId", "createdAt", "updatedAt", title, "posterPath",
+		       "releaseDate", "tmdbScore", score, watched, "userId"
+		FROM "Movie"
+		WHERE "userId" = $1
+	`
+	args := []interface{}{userID}
+
+	// Keyset pagination, with the ID breaking ties between movies added together
+	if after != nil {
+		query += ` AND ("createdAt", id) > ($2, $3)`
+		args = append(args, after.CreatedAt, after.ID)
+	}
+	query += fmt.Sprintf(` ORDER BY "createdAt" ASC, id ASC LIMIT %d`, eachPageSize)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movies: %w", err)
+	}
+	defer rows.Close()
+
+	page := make([]models.Movie, 0, eachPageSize)
+	for rows.Next() {
+		var movie models.Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.TmdbID,
+			&movie.CreatedAt,
+			&movie.UpdatedAt,
+			&movie.Title,
+			&movie.PosterPath,
+			&movie.ReleaseDate,
+			&movie.TmdbScore,
+			&movie.Score,
+			&movie.Watched,
+			&movie.UserID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan movie: %w", err)
+		}
+		page = append(page, movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating movies: %w", err)
+	}
+
+	return page, nil
+}
+
 // Create creates a new movie
 func (s *MovieService) Create(ctx context.Context, userID uuid.UUID, input models.CreateMovieInput) (*models.Movie, error) {
 	score := 0.0
@@ -157,7 +238,7 @@ func (s *MovieService) Create(ctx context.Context, userID uuid.UUID, input model
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to create movie: %w", err)
+		return nil, fmt.Errorf("failed to create movie: %w", apperror.FromPostgres(err))
 	}
 
 	return &movie, nil
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// ErrInvalidIDToken is returned when an ID token fails verification
+	ErrInvalidIDToken = errors.New("invalid id token")
+	// ErrMissingClaim is returned when a mapped claim is absent from the ID token and userinfo
+	ErrMissingClaim = errors.New("missing claim")
+)
+
+const (
+	// oidcFetchTimeout bounds discovery requests, so a hung issuer fails logins
+	// quickly instead of piling them up
+	oidcFetchTimeout = 5 * time.Second
+	// discoveryTTL is how long a discovery document is used before it is fetched again
+	discoveryTTL = time.Hour
+	// discoveryRetryInterval is how long a stale document is kept after a failed refresh
+	discoveryRetryInterval = 30 * time.Second
+)
+
+// OIDCClaims maps user fields to claim names
+type OIDCClaims struct {
+	Subject string
+	Email   string
+	Name    string
+}
+
+// OIDCProviderConfig holds the configuration of one OpenID Connect provider
+type OIDCProviderConfig struct {
+	// Name identifies the provider in routes, e.g. /auth/oidc/{name}/login
+	Name        string
+	DisplayName string
+	// Issuer must match the issuer in the discovery document exactly
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	Claims       OIDCClaims
+	// HTTPClient is used for discovery, JWKS, token and userinfo requests
+	HTTPClient *http.Client
+}
+
+// OIDCIdentity is the user identity asserted by a provider
+type OIDCIdentity struct {
+	Subject string
+	Email   string
+	Name    string
+}
+
+// OIDCProvider signs users in against an OpenID Connect issuer. Discovery happens
+// on first use so an unreachable issuer does not stop the server from starting, and
+// is repeated every discoveryTTL so rotated endpoints and keys are picked up.
+type OIDCProvider struct {
+	cfg    OIDCProviderConfig
+	client *http.Client
+
+	// fetches shares discovery requests between concurrent callers
+	fetches singleflight.Group
+
+	// mu guards the fields below. It is never held during a request to the issuer.
+	mu        sync.Mutex
+	provider  *oidc.Provider
+	refreshAt time.Time
+}
+
+// NewOIDCProvider creates a new OIDCProvider
+func NewOIDCProvider(cfg OIDCProviderConfig) *OIDCProvider {
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = cfg.Name
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	if cfg.Claims.Subject == "" {
+		cfg.Claims.Subject = "sub"
+	}
+	if cfg.Claims.Email == "" {
+		cfg.Claims.Email = "email"
+	}
+	if cfg.Claims.Name == "" {
+		cfg.Claims.Name = "name"
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &OIDCProvider{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+// Name returns the provider's route name
+func (p *OIDCProvider) Name() string {
+	return p.cfg.Name
+}
+
+// DisplayName returns the provider's name shown on the login page
+func (p *OIDCProvider) DisplayName() string {
+	return p.cfg.DisplayName
+}
+
+// OAuth2Config returns the OAuth2 configuration built from the discovery document
+func (p *OIDCProvider) OAuth2Config(ctx context.Context) (*oauth2.Config, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.oauth2Config(provider), nil
+}
+
+// Authenticate exchanges an authorization code, verifies the returned ID token against
+// the nonce and maps its claims to an identity
+func (p *OIDCProvider) Authenticate(ctx context.Context, code, verifier, nonce string) (*OIDCIdentity, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = oidc.ClientContext(ctx, p.client)
+	token, err := p.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in token response", ErrInvalidIDToken)
+	}
+
+	claims, err := p.verify(ctx, provider, rawIDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &OIDCIdentity{
+		Subject: claimString(claims, p.cfg.Claims.Subject),
+		Email:   claimString(claims, p.cfg.Claims.Email),
+		Name:    claimString(claims, p.cfg.Claims.Name),
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w: %s", ErrMissingClaim, p.cfg.Claims.Subject)
+	}
+
+	// Many issuers keep profile claims out of the ID token, fall back to userinfo
+	if (identity.Email == "" || identity.Name == "") && provider.UserInfoEndpoint() != "" {
+		if err := p.fillFromUserinfo(ctx, provider, token, claimString(claims, "sub"), identity); err != nil {
+			return nil, err
+		}
+	}
+	if identity.Email == "" {
+		return nil, fmt.Errorf("%w: %s", ErrMissingClaim, p.cfg.Claims.Email)
+	}
+	if identity.Name == "" {
+		identity.Name = identity.Email
+	}
+
+	return identity, nil
+}
+
+// VerifyIDToken checks an ID token's signature, issuer, audience, authorized party,
+// expiry and nonce and returns its claims
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (map[string]interface{}, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.verify(oidc.ClientContext(ctx, p.client), provider, rawIDToken, nonce)
+}
+
+// verify checks an ID token against a discovered provider. Signing algorithms are
+// limited to the ones the issuer advertises, RS256 when it advertises none.
+func (p *OIDCProvider) verify(ctx context.Context, provider *oidc.Provider, rawIDToken, nonce string) (map[string]interface{}, error) {
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	var raw json.RawMessage
+	if err := idToken.Claims(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	claims, err := decodeClaims(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	// go-oidc checks the audience but leaves the authorized party to us
+	if azp := claimString(claims, "azp"); azp != "" && azp != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: unexpected authorized party", ErrInvalidIDToken)
+	}
+	if idToken.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+// discover returns the issuer's provider, loading the discovery document on first use
+// and again once it is older than discoveryTTL. A failed refresh keeps the previous
+// document and is retried after discoveryRetryInterval.
+func (p *OIDCProvider) discover(ctx context.Context) (*oidc.Provider, error) {
+	p.mu.Lock()
+	provider, fresh := p.provider, time.Now().Before(p.refreshAt)
+	p.mu.Unlock()
+	if provider != nil && fresh {
+		return provider, nil
+	}
+
+	res, err := p.fetchShared(ctx, "discovery", func(ctx context.Context) (interface{}, error) {
+		// Callers that queued up behind a refresh that just finished reuse its result
+		p.mu.Lock()
+		provider, fresh := p.provider, time.Now().Before(p.refreshAt)
+		p.mu.Unlock()
+		if provider != nil && fresh {
+			return provider, nil
+		}
+
+		// The provider keeps this context's client for its key set fetches
+		discovered, err := oidc.NewProvider(oidc.ClientContext(ctx, p.client), p.cfg.Issuer)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if err != nil {
+			if p.provider == nil {
+				return nil, fmt.Errorf("failed to discover %s: %w", p.cfg.Name, err)
+			}
+			p.refreshAt = time.Now().Add(discoveryRetryInterval)
+			return p.provider, nil
+		}
+		p.provider = discovered
+		p.refreshAt = time.Now().Add(discoveryTTL)
+		return discovered, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*oidc.Provider), nil
+}
+
+// fetchShared runs fetch once for concurrent callers sharing the same key. The fetch is
+// detached from any single caller's cancellation so one caller giving up does not fail
+// the others, and is bounded by oidcFetchTimeout instead; each caller still stops
+// waiting when its own context is done.
+func (p *OIDCProvider) fetchShared(ctx context.Context, key string, fetch func(context.Context) (interface{}, error)) (interface{}, error) {
+	ch := p.fetches.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), oidcFetchTimeout)
+		defer cancel()
+		return fetch(ctx)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		return res.Val, res.Err
+	}
+}
+
+// oauth2Config builds the OAuth2 configuration for a discovered provider
+func (p *OIDCProvider) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Scopes:       p.cfg.Scopes,
+		Endpoint:     provider.Endpoint(),
+	}
+}
+
+// fillFromUserinfo completes an identity from the userinfo endpoint, which must
+// describe the same subject as the ID token
+func (p *OIDCProvider) fillFromUserinfo(ctx context.Context, provider *oidc.Provider, token *oauth2.Token, subject string, identity *OIDCIdentity) error {
+	userInfo, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+	if err != nil {
+		return fmt.Errorf("failed to get user info: %w", err)
+	}
+	if userInfo.Subject != subject {
+		return fmt.Errorf("%w: userinfo subject mismatch", ErrInvalidIDToken)
+	}
+
+	var raw json.RawMessage
+	if err := userInfo.Claims(&raw); err != nil {
+		return fmt.Errorf("failed to decode user info: %w", err)
+	}
+	claims, err := decodeClaims(raw)
+	if err != nil {
+		return fmt.Errorf("failed to decode user info: %w", err)
+	}
+
+	if identity.Email == "" {
+		identity.Email = claimString(claims, p.cfg.Claims.Email)
+	}
+	if identity.Name == "" {
+		identity.Name = claimString(claims, p.cfg.Claims.Name)
+	}
+	return nil
+}
+
+// decodeClaims decodes a JSON claims object, keeping numbers as json.Number so large
+// numeric IDs don't lose precision
+func decodeClaims(data []byte) (map[string]interface{}, error) {
+	var claims map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// claimString returns a claim as a string, following dotted paths into nested objects
+// (e.g. "profile.email") and keeping numeric subjects exactly as the issuer sent them
+func claimString(claims map[string]interface{}, name string) string {
+	var value interface{} = claims
+	for _, part := range strings.Split(name, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = obj[part]
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
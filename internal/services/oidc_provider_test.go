@@ -0,0 +1,500 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testClientID = "reelscore"
+
+// testIssuer is a local OpenID Connect issuer serving discovery and a JWKS, and
+// signing ID tokens with the keys it publishes
+type testIssuer struct {
+	srv *httptest.Server
+
+	mu   sync.Mutex
+	keys map[string]crypto.Signer
+
+	// idToken is returned by the token endpoint, userinfo by the userinfo endpoint
+	idToken  string
+	userinfo map[string]interface{}
+
+	discoveryHits atomic.Int64
+	jwksHits      atomic.Int64
+	// failDiscovery makes discovery return an error
+	failDiscovery atomic.Bool
+	// hold, when set, stalls discovery until it is closed
+	hold chan struct{}
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	iss := &testIssuer{keys: make(map[string]crypto.Signer)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		iss.mu.Lock()
+		hold := iss.hold
+		iss.mu.Unlock()
+		if hold != nil {
+			<-hold
+		}
+		iss.discoveryHits.Add(1)
+		if iss.failDiscovery.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                iss.srv.URL,
+			"authorization_endpoint":                iss.srv.URL + "/authorize",
+			"token_endpoint":                        iss.srv.URL + "/token",
+			"userinfo_endpoint":                     iss.srv.URL + "/userinfo",
+			"jwks_uri":                              iss.srv.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256", "PS256", "ES256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		iss.jwksHits.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": iss.jwks()})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		iss.mu.Lock()
+		defer iss.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-1",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     iss.idToken,
+		})
+	})
+	mux.HandleFunc("GET /userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-1" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		iss.mu.Lock()
+		defer iss.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(iss.userinfo)
+	})
+	iss.srv = httptest.NewServer(mux)
+	t.Cleanup(iss.srv.Close)
+	return iss
+}
+
+// addKey publishes a new signing key under kid
+func (iss *testIssuer) addKey(t *testing.T, kid string, key crypto.Signer) {
+	t.Helper()
+
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.keys[kid] = key
+}
+
+// jwks returns the published keys as JSON Web Keys
+func (iss *testIssuer) jwks() []map[string]string {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	var keys []map[string]string
+	for kid, key := range iss.keys {
+		switch k := key.Public().(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, map[string]string{
+				"kty": "RSA", "kid": kid, "use": "sig",
+				"n": b64(k.N.Bytes()), "e": b64(big.NewInt(int64(k.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			size := (k.Curve.Params().BitSize + 7) / 8
+			keys = append(keys, map[string]string{
+				"kty": "EC", "kid": kid, "use": "sig", "crv": k.Curve.Params().Name,
+				"x": b64(k.X.FillBytes(make([]byte, size))), "y": b64(k.Y.FillBytes(make([]byte, size))),
+			})
+		}
+	}
+	return keys
+}
+
+// claims returns valid ID token claims for the test client
+func (iss *testIssuer) claims(nonce string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   iss.srv.URL,
+		"aud":   testClientID,
+		"sub":   "user-1",
+		"email": "user@example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": nonce,
+	}
+}
+
+// sign signs claims with the key published under kid
+func (iss *testIssuer) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	iss.mu.Lock()
+	key := iss.keys[kid]
+	iss.mu.Unlock()
+
+	signed := jwtSegment(t, map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + jwtSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	var err error
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+	case "PS256":
+		signature, err = rsa.SignPSS(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:], nil)
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	default:
+		t.Fatalf("sign: unsupported algorithm %s", alg)
+	}
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// respond sets what the token and userinfo endpoints return
+func (iss *testIssuer) respond(idToken string, userinfo map[string]interface{}) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.idToken = idToken
+	iss.userinfo = userinfo
+}
+
+// provider returns a provider for the test client against the issuer
+func (iss *testIssuer) provider() *OIDCProvider {
+	return NewOIDCProvider(OIDCProviderConfig{
+		Name:     "test",
+		Issuer:   iss.srv.URL,
+		ClientID: testClientID,
+	})
+}
+
+func jwtSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestOIDCVerifyIDTokenValid(t *testing.T) {
+	iss := newTestIssuer(t)
+	iss.addKey(t, "rsa", testRSAKey(t))
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss.addKey(t, "ec", ecKey)
+	p := iss.provider()
+
+	for _, tc := range []struct{ alg, kid string }{
+		{"RS256", "rsa"},
+		{"PS256", "rsa"},
+		{"ES256", "ec"},
+	} {
+		t.Run(tc.alg, func(t *testing.T) {
+			token := iss.sign(t, tc.alg, tc.kid, iss.claims("n-1"))
+
+			claims, err := p.VerifyIDToken(context.Background(), token, "n-1")
+			if err != nil {
+				t.Fatalf("VerifyIDToken: %v", err)
+			}
+			if claimString(claims, "sub") != "user-1" {
+				t.Errorf("sub = %q, want user-1", claimString(claims, "sub"))
+			}
+		})
+	}
+}
+
+func TestOIDCVerifyIDTokenRejectsClaims(t *testing.T) {
+	iss := newTestIssuer(t)
+	iss.addKey(t, "rsa", testRSAKey(t))
+	p := iss.provider()
+
+	tests := []struct {
+		name   string
+		modify func(claims map[string]interface{})
+		nonce  string
+	}{
+		{"wrong audience", func(c map[string]interface{}) { c["aud"] = "someone-else" }, "n-1"},
+		{"audience list without us", func(c map[string]interface{}) { c["aud"] = []string{"a", "b"} }, "n-1"},
+		{"wrong authorized party", func(c map[string]interface{}) { c["azp"] = "someone-else" }, "n-1"},
+		{"wrong issuer", func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }, "n-1"},
+		{"expired", func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, "n-1"},
+		{"missing expiry", func(c map[string]interface{}) { delete(c, "exp") }, "n-1"},
+		{"not yet valid", func(c map[string]interface{}) { c["nbf"] = time.Now().Add(10 * time.Minute).Unix() }, "n-1"},
+		{"bad nonce", func(c map[string]interface{}) {}, "n-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := iss.claims("n-1")
+			tt.modify(claims)
+			token := iss.sign(t, "RS256", "rsa", claims)
+
+			if _, err := p.VerifyIDToken(context.Background(), token, tt.nonce); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("VerifyIDToken() error = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestOIDCVerifyIDTokenRejectsAlgorithms(t *testing.T) {
+	iss := newTestIssuer(t)
+	key := testRSAKey(t)
+	iss.addKey(t, "rsa", key)
+	p := iss.provider()
+
+	payload := jwtSegment(t, iss.claims("n-1"))
+
+	// alg none, with and without a signature
+	unsigned := jwtSegment(t, map[string]string{"alg": "none", "kid": "rsa"}) + "." + payload
+	for _, token := range []string{unsigned + ".", unsigned + ".c2ln"} {
+		if _, err := p.VerifyIDToken(context.Background(), token, "n-1"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("alg none: VerifyIDToken() error = %v, want ErrInvalidIDToken", err)
+		}
+	}
+
+	// HS256 keyed with the issuer's public key, the classic algorithm confusion attack
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := jwtSegment(t, map[string]string{"alg": "HS256", "kid": "rsa"}) + "." + payload
+	mac := hmac.New(sha256.New, publicKey)
+	mac.Write([]byte(signed))
+	token := signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	if _, err := p.VerifyIDToken(context.Background(), token, "n-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("HS256: VerifyIDToken() error = %v, want ErrInvalidIDToken", err)
+	}
+
+	// An RSA key can't verify an EC algorithm
+	mismatched := jwtSegment(t, map[string]string{"alg": "ES256", "kid": "rsa"}) + "." + payload + "." + base64.RawURLEncoding.EncodeToString(make([]byte, 64))
+	if _, err := p.VerifyIDToken(context.Background(), mismatched, "n-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("ES256 with RSA key: VerifyIDToken() error = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestOIDCVerifyIDTokenUnknownKeyRefetches(t *testing.T) {
+	iss := newTestIssuer(t)
+	iss.addKey(t, "old", testRSAKey(t))
+	p := iss.provider()
+	ctx := context.Background()
+
+	if _, err := p.VerifyIDToken(ctx, iss.sign(t, "RS256", "old", iss.claims("n-1")), "n-1"); err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if got := iss.jwksHits.Load(); got != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", got)
+	}
+
+	// The issuer rotates keys, and the unknown key ID triggers a refetch
+	iss.addKey(t, "new", testRSAKey(t))
+	if _, err := p.VerifyIDToken(ctx, iss.sign(t, "RS256", "new", iss.claims("n-1")), "n-1"); err != nil {
+		t.Fatalf("VerifyIDToken with rotated key: %v", err)
+	}
+	if got := iss.jwksHits.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", got)
+	}
+
+	// A key ID the issuer doesn't have is still refused after refetching
+	forged := jwtSegment(t, map[string]string{"alg": "RS256", "kid": "missing"}) + "." + jwtSegment(t, iss.claims("n-1")) + ".c2ln"
+	if _, err := p.VerifyIDToken(ctx, forged, "n-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("VerifyIDToken() error = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestOIDCLargeNumericSubject(t *testing.T) {
+	iss := newTestIssuer(t)
+	iss.addKey(t, "rsa", testRSAKey(t))
+	p := iss.provider()
+
+	claims := iss.claims("n-1")
+	claims["uid"] = json.RawMessage("12345678901234567890")
+	token := iss.sign(t, "RS256", "rsa", claims)
+
+	verified, err := p.VerifyIDToken(context.Background(), token, "n-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if got := claimString(verified, "uid"); got != "12345678901234567890" {
+		t.Errorf("uid = %q, want 12345678901234567890", got)
+	}
+}
+
+func TestOIDCAuthenticateUserinfo(t *testing.T) {
+	tests := []struct {
+		name        string
+		userinfoSub string
+		wantErr     error
+	}{
+		{"same subject", "user-1", nil},
+		{"different subject", "user-2", ErrInvalidIDToken},
+		{"no subject", "", ErrInvalidIDToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iss := newTestIssuer(t)
+			iss.addKey(t, "rsa", testRSAKey(t))
+
+			// The user is mapped from a custom claim, the userinfo check still uses sub
+			claims := iss.claims("n-1")
+			claims["uid"] = 42
+			delete(claims, "email")
+			iss.respond(iss.sign(t, "RS256", "rsa", claims), map[string]interface{}{
+				"sub":   tt.userinfoSub,
+				"uid":   42,
+				"email": "user@example.com",
+				"name":  "User One",
+			})
+			p := NewOIDCProvider(OIDCProviderConfig{
+				Name:     "test",
+				Issuer:   iss.srv.URL,
+				ClientID: testClientID,
+				Claims:   OIDCClaims{Subject: "uid"},
+			})
+
+			identity, err := p.Authenticate(context.Background(), "code-1", "verifier-1", "n-1")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			want := OIDCIdentity{Subject: "42", Email: "user@example.com", Name: "User One"}
+			if *identity != want {
+				t.Errorf("identity = %+v, want %+v", *identity, want)
+			}
+		})
+	}
+}
+
+func TestOIDCDiscoveryRefresh(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := iss.provider()
+	ctx := context.Background()
+	expire := func() {
+		p.mu.Lock()
+		p.refreshAt = time.Now().Add(-time.Second)
+		p.mu.Unlock()
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.OAuth2Config(ctx); err != nil {
+			t.Fatalf("OAuth2Config: %v", err)
+		}
+	}
+	if got := iss.discoveryHits.Load(); got != 1 {
+		t.Fatalf("discovery fetched %d times, want 1", got)
+	}
+
+	// A stale document is fetched again
+	expire()
+	if _, err := p.OAuth2Config(ctx); err != nil {
+		t.Fatalf("OAuth2Config: %v", err)
+	}
+	if got := iss.discoveryHits.Load(); got != 2 {
+		t.Fatalf("discovery fetched %d times after expiry, want 2", got)
+	}
+
+	// A failed refresh keeps the previous document and waits before retrying
+	iss.failDiscovery.Store(true)
+	expire()
+	for i := 0; i < 2; i++ {
+		if _, err := p.OAuth2Config(ctx); err != nil {
+			t.Fatalf("OAuth2Config with a failing issuer: %v", err)
+		}
+	}
+	if got := iss.discoveryHits.Load(); got != 3 {
+		t.Errorf("discovery fetched %d times after a failed refresh, want 3", got)
+	}
+
+	// Without a previous document the failure is returned
+	if _, err := iss.provider().OAuth2Config(ctx); err == nil {
+		t.Error("OAuth2Config succeeded against a failing issuer")
+	}
+}
+
+func TestOIDCDiscoveryDoesNotBlockOnHungIssuer(t *testing.T) {
+	iss := newTestIssuer(t)
+	hold := make(chan struct{})
+	iss.hold = hold
+	defer close(hold)
+	p := iss.provider()
+
+	// Each caller gives up at its own deadline rather than queueing behind the fetch
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := p.OAuth2Config(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("OAuth2Config() error = %v, want context.DeadlineExceeded", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("OAuth2Config took %v with a hung issuer", elapsed)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The provider's lock stays free while discovery hangs
+	done := make(chan struct{})
+	go func() {
+		p.mu.Lock()
+		p.mu.Unlock()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("provider lock held during discovery")
+	}
+}
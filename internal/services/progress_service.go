@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/liamwears/reelscore/internal/models"
+)
+
+var (
+	// ErrEpisodeNotAired is returned when marking an episode that has not aired or does not exist
+	ErrEpisodeNotAired = errors.New("episode has not aired")
+	// ErrInvalidProgressScope is returned for an unknown progress scope
+	ErrInvalidProgressScope = errors.New("invalid progress scope")
+)
+
+// ProgressService handles episode-level watch progress for series
+type ProgressService struct {
+	db           *pgxpool.Pool
+	serieService *SerieService
+	tmdbService  *TMDBService
+}
+
+// NewProgressService creates a new ProgressService
+func NewProgressService(db *pgxpool.Pool, serieService *SerieService, tmdbService *TMDBService) *ProgressService {
+	return &ProgressService{
+		db:           db,
+		serieService: serieService,
+		tmdbService:  tmdbService,
+	}
+}
+
+// airedSeason holds the aired episode numbers of a season, in order
+type airedSeason struct {
+	number   int
+	episodes []int
+}
+
+// Get computes a serie's watch progress against the episodes aired according to TMDB
+func (s *ProgressService) Get(ctx context.Context, serieID, userID uuid.UUID) (*models.SerieProgress, error) {
+	serie, err := s.serieService.Get(ctx, serieID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	aired, err := s.airedSeasons(ctx, serie.TmdbID)
+	if err != nil {
+		return nil, err
+	}
+
+	watched, err := s.watchedEpisodes(ctx, serieID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildProgress(serie, aired, watched), nil
+}
+
+// Mark marks episodes as watched according to the input scope and flips the serie to
+// watched once all of its aired episodes are watched
+func (s *ProgressService) Mark(ctx context.Context, serieID, userID uuid.UUID, input models.MarkProgressInput) (*models.SerieProgress, error) {
+	if !input.Scope.IsValid() {
+		return nil, ErrInvalidProgressScope
+	}
+
+	serie, err := s.serieService.Get(ctx, serieID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	aired, err := s.airedSeasons(ctx, serie.TmdbID)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := episodesInScope(aired, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Insert all episodes in one batch, ignoring ones already marked
+	batch := &pgx.Batch{}
+	for _, ref := range refs {
+		batch.Queue(`
+			INSERT INTO "EpisodeProgress" ("serieId", "userId", "seasonNumber", "episodeNumber")
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT ("serieId", "seasonNumber", "episodeNumber") DO NOTHING
+		`, serieID, userID, ref.SeasonNumber, ref.EpisodeNumber)
+	}
+	if err := s.db.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, fmt.Errorf("failed to mark episodes: %w", err)
+	}
+
+	watched, err := s.watchedEpisodes(ctx, serieID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flip the serie to watched once every aired episode is watched
+	progress := buildProgress(serie, aired, watched)
+	if !serie.Watched && allAiredWatched(progress) {
+		done := true
+		if _, err := s.serieService.Update(ctx, userID, models.UpdateSerieInput{
+			ID:      serieID,
+			Watched: &done,
+		}); err != nil {
+			return nil, err
+		}
+		progress.Watched = true
+		progress.MarkedWatched = true
+	}
+
+	return progress, nil
+}
+
+// Unmark removes a watched episode, or the whole season when episode is 0, and flips a
+// watched serie back to unwatched once one of its aired episodes is no longer watched
+func (s *ProgressService) Unmark(ctx context.Context, serieID, userID uuid.UUID, season, episode int) (*models.SerieProgress, error) {
+	query := `DELETE FROM "EpisodeProgress" WHERE "serieId" = $1 AND "userId" = $2 AND "seasonNumber" = $3`
+	args := []interface{}{serieID, userID, season}
+
+	if episode > 0 {
+		query += ` AND "episodeNumber" = $4`
+		args = append(args, episode)
+	}
+
+	tag, err := s.db.Exec(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmark episodes: %w", err)
+	}
+
+	progress, err := s.Get(ctx, serieID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag.RowsAffected() > 0 && progress.Watched && progress.NextEpisode != nil {
+		watched := false
+		if _, err := s.serieService.Update(ctx, userID, models.UpdateSerieInput{
+			ID:      serieID,
+			Watched: &watched,
+		}); err != nil {
+			return nil, err
+		}
+		progress.Watched = false
+	}
+
+	return progress, nil
+}
+
+// airedSeasons returns the aired episodes of every regular season, skipping specials.
+// They are worked out from the episode counts in the serie details and its last aired
+// episode, so progress costs one TMDB request, served from the cache when enabled,
+// rather than one per season.
+func (s *ProgressService) airedSeasons(ctx context.Context, tmdbID int) ([]airedSeason, error) {
+	details, err := s.tmdbService.GetTVDetails(ctx, tmdbID)
+	if err != nil {
+		return nil, err
+	}
+
+	return airedSeasonsFromDetails(details, time.Now().Format("2006-01-02")), nil
+}
+
+// airedSeasonsFromDetails lists the episodes aired by today. Seasons that have premiered
+// count all their episodes, except the one airing now, which counts up to the last
+// aired episode.
+func airedSeasonsFromDetails(details *TMDBTVDetails, today string) []airedSeason {
+	var seasons []airedSeason
+	for _, summary := range details.Seasons {
+		if summary.SeasonNumber < 1 {
+			continue
+		}
+
+		count := 0
+		// Air dates are ISO formatted so they compare lexically
+		if summary.AirDate != "" && summary.AirDate <= today {
+			count = summary.EpisodeCount
+		}
+		if last := details.LastEpisodeToAir; last != nil && last.SeasonNumber == summary.SeasonNumber && last.EpisodeNumber < count {
+			count = last.EpisodeNumber
+		}
+
+		aired := airedSeason{number: summary.SeasonNumber}
+		for episode := 1; episode <= count; episode++ {
+			aired.episodes = append(aired.episodes, episode)
+		}
+		seasons = append(seasons, aired)
+	}
+
+	sort.Slice(seasons, func(i, j int) bool { return seasons[i].number < seasons[j].number })
+	return seasons
+}
+
+// watchedEpisodes returns the set of episodes marked as watched
+func (s *ProgressService) watchedEpisodes(ctx context.Context, serieID, userID uuid.UUID) (map[models.EpisodeRef]bool, error) {
+	query := `
+		SELECT "seasonNumber", "episodeNumber"
+		FROM "EpisodeProgress"
+		WHERE "serieId" = $1 AND "userId" = $2
+	`
+
+	rows, err := s.db.Query(ctx, query, serieID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query episode progress: %w", err)
+	}
+	defer rows.Close()
+
+	watched := make(map[models.EpisodeRef]bool)
+	for rows.Next() {
+		var ref models.EpisodeRef
+		if err := rows.Scan(&ref.SeasonNumber, &ref.EpisodeNumber); err != nil {
+			return nil, fmt.Errorf("failed to scan episode progress: %w", err)
+		}
+		watched[ref] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating episode progress: %w", err)
+	}
+
+	return watched, nil
+}
+
+// episodesInScope resolves a progress input to the aired episodes it covers
+func episodesInScope(aired []airedSeason, input models.MarkProgressInput) ([]models.EpisodeRef, error) {
+	var refs []models.EpisodeRef
+
+	for _, season := range aired {
+		switch input.Scope {
+		case models.ProgressScopeEpisode:
+			if season.number != input.Season {
+				continue
+			}
+			for _, episode := range season.episodes {
+				if episode == input.Episode {
+					refs = append(refs, models.EpisodeRef{SeasonNumber: season.number, EpisodeNumber: episode})
+				}
+			}
+		case models.ProgressScopeSeason:
+			if season.number != input.Season {
+				continue
+			}
+			for _, episode := range season.episodes {
+				refs = append(refs, models.EpisodeRef{SeasonNumber: season.number, EpisodeNumber: episode})
+			}
+		case models.ProgressScopeUpTo:
+			if season.number > input.Season {
+				continue
+			}
+			for _, episode := range season.episodes {
+				if season.number == input.Season && episode > input.Episode {
+					continue
+				}
+				refs = append(refs, models.EpisodeRef{SeasonNumber: season.number, EpisodeNumber: episode})
+			}
+		}
+	}
+
+	if len(refs) == 0 {
+		return nil, ErrEpisodeNotAired
+	}
+
+	return refs, nil
+}
+
+// buildProgress summarises watched episodes against aired seasons
+func buildProgress(serie *models.Serie, aired []airedSeason, watched map[models.EpisodeRef]bool) *models.SerieProgress {
+	progress := &models.SerieProgress{
+		SerieID: serie.ID,
+		TmdbID:  serie.TmdbID,
+		Seasons: []models.SeasonProgress{},
+		Watched: serie.Watched,
+	}
+
+	for _, season := range aired {
+		sp := models.SeasonProgress{
+			SeasonNumber:    season.number,
+			AiredEpisodes:   len(season.episodes),
+			WatchedEpisodes: []int{},
+		}
+
+		for _, episode := range season.episodes {
+			ref := models.EpisodeRef{SeasonNumber: season.number, EpisodeNumber: episode}
+			if watched[ref] {
+				sp.WatchedEpisodes = append(sp.WatchedEpisodes, episode)
+			} else if progress.NextEpisode == nil {
+				progress.NextEpisode = &ref
+			}
+		}
+
+		sp.Complete = sp.AiredEpisodes > 0 && len(sp.WatchedEpisodes) == sp.AiredEpisodes
+		progress.AiredEpisodes += sp.AiredEpisodes
+		progress.WatchedEpisodes += len(sp.WatchedEpisodes)
+		progress.Seasons = append(progress.Seasons, sp)
+	}
+
+	if progress.AiredEpisodes > 0 {
+		percent := float64(progress.WatchedEpisodes) / float64(progress.AiredEpisodes) * 100
+		progress.Percent = math.Round(percent*10) / 10
+	}
+
+	return progress
+}
+
+// allAiredWatched reports whether every aired episode of a serie is watched
+func allAiredWatched(progress *models.SerieProgress) bool {
+	return progress.AiredEpisodes > 0 && progress.NextEpisode == nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/liamwears/reelscore/internal/models"
+)
+
+func TestAiredSeasonsFromDetails(t *testing.T) {
+	details := &TMDBTVDetails{
+		LastEpisodeToAir: &TMDBEpisode{SeasonNumber: 2, EpisodeNumber: 3},
+		Seasons: []TMDBSeasonSummary{
+			{SeasonNumber: 2, EpisodeCount: 8, AirDate: "2026-09-01"},
+			{SeasonNumber: 0, EpisodeCount: 4, AirDate: "2024-12-24"},
+			{SeasonNumber: 1, EpisodeCount: 3, AirDate: "2025-01-10"},
+			{SeasonNumber: 3, EpisodeCount: 10, AirDate: "2027-03-01"},
+		},
+	}
+
+	got := airedSeasonsFromDetails(details, "2026-09-20")
+	want := []airedSeason{
+		{number: 1, episodes: []int{1, 2, 3}},
+		{number: 2, episodes: []int{1, 2, 3}},
+		{number: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("airedSeasonsFromDetails() = %+v, want %+v", got, want)
+	}
+
+}
+
+func TestAiredSeasonsFromDetailsNothingAired(t *testing.T) {
+	details := &TMDBTVDetails{
+		Seasons: []TMDBSeasonSummary{{SeasonNumber: 1, EpisodeCount: 6, AirDate: "2027-01-01"}},
+	}
+
+	progress := buildProgress(&models.Serie{}, airedSeasonsFromDetails(details, "2026-09-20"), nil)
+	if allAiredWatched(progress) {
+		t.Error("allAiredWatched() = true before the premiere")
+	}
+}
+
+func TestAllAiredWatched(t *testing.T) {
+	aired := []airedSeason{
+		{number: 1, episodes: []int{1, 2}},
+		{number: 2, episodes: []int{1, 2, 3}},
+	}
+	ref := func(season, episode int) models.EpisodeRef {
+		return models.EpisodeRef{SeasonNumber: season, EpisodeNumber: episode}
+	}
+
+	tests := []struct {
+		name    string
+		watched []models.EpisodeRef
+		want    bool
+	}{
+		{"nothing watched", nil, false},
+		{"mark finale only", []models.EpisodeRef{ref(2, 3)}, false},
+		{"finale with a gap", []models.EpisodeRef{ref(1, 1), ref(1, 2), ref(2, 1), ref(2, 3)}, false},
+		{"every aired episode", []models.EpisodeRef{ref(1, 1), ref(1, 2), ref(2, 1), ref(2, 2), ref(2, 3)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watched := make(map[models.EpisodeRef]bool)
+			for _, r := range tt.watched {
+				watched[r] = true
+			}
+
+			if got := allAiredWatched(buildProgress(&models.Serie{}, aired, watched)); got != tt.want {
+				t.Errorf("allAiredWatched() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
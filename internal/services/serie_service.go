@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/liamwears/reelscore/internal/apperror"
 	"github.com/liamwears/reelscore/internal/models"
 )
 
@@ -49,6 +50,10 @@ func (s *SerieService) List(ctx context.Context, userID uuid.UUID, input models.
 		args = append(args, "%"+input.Query+"%")
 	}
 
+	// Add sort-independent filters
+	baseQuery, args = applyListFilters(baseQuery, args, input.ListOptions, "firstAired")
+	argCount = len(args)
+
 	// Count total
 	var total int
 	countQuery := "SELECT COUNT(*) " + baseQuery
@@ -61,8 +66,7 @@ func (s *SerieService) List(ctx context.Context, userID uuid.UUID, input models.
 	query := `
 		SELECT id, "tmdbId", "createdAt", "updatedAt", title, "posterPath",
 		       "firstAired", "tmdbScore", score, watched, "userId"
-	` + baseQuery + `
-		ORDER BY "tmdbScore" DESC
+	` + baseQuery + orderByClause(input.ListOptions, "firstAired") + `
 		LIMIT $` + fmt.Sprintf("%d", argCount+1) + ` OFFSET $` + fmt.Sprintf("%d", argCount+2)
 
 	args = append(args, input.Limit, offset)
@@ -109,6 +113,83 @@ func (s *SerieService) List(ctx context.Context, userID uuid.UUID, input models.
 	}, nil
 }
 
+// Each passes every serie in a user's library to fn, oldest first, without loading
+// the whole library into memory. Series are read a page at a time and the connection
+// goes back to the pool before fn sees them, so a slow consumer, like a client
+// downloading an export, never holds one.
+func (s *SerieService) Each(ctx context.Context, userID uuid.UUID, fn func(models.Serie) error) error {
+	var after *models.Serie
+	for {
+		page, err := s.eachPage(ctx, userID, after)
+		if err != nil {
+			return err
+		}
+
+		for _, serie := range page {
+			if err := fn(serie); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < eachPageSize {
+			return nil
+		}
+		after = &page[len(page)-1]
+	}
+}
+
+// eachPage reads the page of series following after, or the first page when after is nil
+func (s *SerieService) eachPage(ctx context.Context, userID uuid.UUID, after *models.Serie) ([]models.Serie, error) {
+	query := `
+		SELECT id, "tmdbId", "createdAt", "updatedAt", title, "posterPath",
+		       "firstAired", "tmdbScore", score, watched, "userId"
+		FROM "Serie"
+		WHERE "userId" = $1
+	`
+	args := []interface{}{userID}
+
+	// Keyset pagination, with the ID breaking ties between series added together
+	if after != nil {
+		query += ` AND ("createdAt", id) > ($2, $3)`
+		args = append(args, after.CreatedAt, after.ID)
+	}
+	query += fmt.Sprintf(` ORDER BY "createdAt" ASC, id ASC LIMIT %d`, eachPageSize)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query series: %w", err)
+	}
+	defer rows.Close()
+
+	page := make([]models.Serie, 0, eachPageSize)
+	for rows.Next() {
+		var serie models.Serie
+		err := rows.Scan(
+			&serie.ID,
+			&serie.TmdbID,
+			&serie.CreatedAt,
+			&serie.UpdatedAt,
+			&serie.Title,
+			&serie.PosterPath,
+			&serie.FirstAired,
+			&serie.TmdbScore,
+			&serie.Score,
+			&serie.Watched,
+			&serie.UserID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan serie: %w", err)
+		}
+		page = append(page, serie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating series: %w", err)
+	}
+
+	return page, nil
+}
+
 // Create creates a new serie
 func (s *SerieService) Create(ctx context.Context, userID uuid.UUID, input models.CreateSerieInput) (*models.Serie, error) {
 	score := 0.0
@@ -157,7 +238,7 @@ func (s *SerieService) Create(ctx context.Context, userID uuid.UUID, input model
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to create serie: %w", err)
+		return nil, fmt.Errorf("failed to create serie: %w", apperror.FromPostgres(err))
 	}
 
 	return &serie, nil
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TMDBCache caches raw TMDB responses in Redis
+type TMDBCache struct {
+	redis      *redis.Client
+	ttls       []TMDBCacheTTL
+	defaultTTL time.Duration
+	staleTTL   time.Duration
+
+	hits   atomic.Int64
+	stale  atomic.Int64
+	misses atomic.Int64
+
+	mu         sync.Mutex
+	refreshing map[string]bool
+}
+
+// TMDBCacheTTL maps an endpoint prefix to how long its responses stay fresh
+type TMDBCacheTTL struct {
+	Prefix string
+	TTL    time.Duration
+}
+
+// TMDBCacheConfig holds TMDB cache configuration
+type TMDBCacheConfig struct {
+	// TTLs are matched against the endpoint in order, first match wins
+	TTLs       []TMDBCacheTTL
+	DefaultTTL time.Duration
+	// StaleTTL is how long an expired entry may still be served while it is refreshed
+	StaleTTL time.Duration
+}
+
+// DefaultTMDBCacheTTLs returns the per-endpoint TTLs used when none are configured
+func DefaultTMDBCacheTTLs() []TMDBCacheTTL {
+	return []TMDBCacheTTL{
+		{Prefix: "/discover/", TTL: 15 * time.Minute},
+		{Prefix: "/search/", TTL: 30 * time.Minute},
+		{Prefix: "/movie/", TTL: 24 * time.Hour},
+		{Prefix: "/tv/", TTL: 24 * time.Hour},
+	}
+}
+
+// TMDBCacheStats holds cache hit/miss counters
+type TMDBCacheStats struct {
+	Hits   int64 `json:"hits"`
+	Stale  int64 `json:"stale"`
+	Misses int64 `json:"misses"`
+}
+
+// tmdbCacheEntry is the value stored in Redis for each cached response
+type tmdbCacheEntry struct {
+	Body      []byte    `json:"body"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// NewTMDBCache creates a new TMDB response cache
+func NewTMDBCache(client *redis.Client, cfg TMDBCacheConfig) *TMDBCache {
+	if cfg.TTLs == nil {
+		cfg.TTLs = DefaultTMDBCacheTTLs()
+	}
+	if cfg.DefaultTTL == 0 {
+		cfg.DefaultTTL = time.Hour
+	}
+	if cfg.StaleTTL == 0 {
+		cfg.StaleTTL = time.Hour
+	}
+	return &TMDBCache{
+		redis:      client,
+		ttls:       cfg.TTLs,
+		defaultTTL: cfg.DefaultTTL,
+		staleTTL:   cfg.StaleTTL,
+		refreshing: make(map[string]bool),
+	}
+}
+
+// Stats returns the current cache counters
+func (c *TMDBCache) Stats() TMDBCacheStats {
+	return TMDBCacheStats{
+		Hits:   c.hits.Load(),
+		Stale:  c.stale.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+// Fetch returns the cached response for endpoint and params, calling fetch on a miss.
+// Entries past their TTL but within the stale window are returned immediately and
+// refreshed in the background.
+func (c *TMDBCache) Fetch(ctx context.Context, endpoint string, params map[string]string, fetch func(context.Context) ([]byte, error)) ([]byte, error) {
+	key := c.key(requestKey(endpoint, params))
+	ttl := c.ttlFor(endpoint)
+
+	entry, err := c.get(ctx, key)
+	if err != nil && err != redis.Nil {
+		// Redis trouble should never break TMDB lookups
+		slog.WarnContext(ctx, "TMDB cache read failed", "endpoint", endpoint, "error", err)
+	}
+
+	if entry != nil {
+		if time.Since(entry.FetchedAt) < ttl {
+			c.hits.Add(1)
+			return entry.Body, nil
+		}
+
+		c.stale.Add(1)
+		c.refresh(ctx, key, ttl, fetch)
+		return entry.Body, nil
+	}
+
+	c.misses.Add(1)
+	body, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.set(ctx, key, ttl, body); err != nil {
+		slog.WarnContext(ctx, "TMDB cache write failed", "endpoint", endpoint, "error", err)
+	}
+
+	return body, nil
+}
+
+// refresh re-fetches a stale entry in the background, at most once per key. The
+// refresh outlives the request but keeps its context values for logging.
+func (c *TMDBCache) refresh(ctx context.Context, key string, ttl time.Duration, fetch func(context.Context) ([]byte, error)) {
+	c.mu.Lock()
+	if c.refreshing[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = true
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 15*time.Second)
+		defer cancel()
+
+		body, err := fetch(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "TMDB cache refresh failed", "key", key, "error", err)
+			return
+		}
+
+		if err := c.set(ctx, key, ttl, body); err != nil {
+			slog.WarnContext(ctx, "TMDB cache write failed", "key", key, "error", err)
+		}
+	}()
+}
+
+// get reads an entry from Redis
+func (c *TMDBCache) get(ctx context.Context, key string) (*tmdbCacheEntry, error) {
+	val, err := c.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var entry tmdbCacheEntry
+	if err := json.Unmarshal(val, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cache entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// set writes an entry to Redis, keeping it around for the stale window after it expires
+func (c *TMDBCache) set(ctx context.Context, key string, ttl time.Duration, body []byte) error {
+	val, err := json.Marshal(tmdbCacheEntry{
+		Body:      body,
+		FetchedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	return c.redis.Set(ctx, key, val, ttl+c.staleTTL).Err()
+}
+
+// ttlFor returns the freshness TTL for an endpoint
+func (c *TMDBCache) ttlFor(endpoint string) time.Duration {
+	for _, t := range c.ttls {
+		if strings.HasPrefix(endpoint, t.Prefix) {
+			return t.TTL
+		}
+	}
+	return c.defaultTTL
+}
+
+// key hashes a request key into a Redis key
+func (c *TMDBCache) key(requestKey string) string {
+	sum := sha256.Sum256([]byte(requestKey))
+	return fmt.Sprintf("tmdb:%s", hex.EncodeToString(sum[:]))
+}
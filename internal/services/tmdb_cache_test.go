@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// testRedis connects to the Redis at REDIS_ADDR, skipping the test when it is unset
+func testRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR not set, skipping Redis test")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("failed to connect to Redis at %s: %v", addr, err)
+	}
+	return client
+}
+
+// testCacheParams returns params no other test run uses and deletes their cache entry
+// when the test ends
+func testCacheParams(t *testing.T, c *TMDBCache, endpoint string) map[string]string {
+	t.Helper()
+
+	params := map[string]string{"test": uuid.NewString()}
+	t.Cleanup(func() {
+		c.redis.Del(context.Background(), c.key(requestKey(endpoint, params)))
+	})
+	return params
+}
+
+// newFakeTMDB starts a TMDB stand-in that counts requests and answers them with handler
+func newFakeTMDB(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+// discoverResponse answers with a one movie page, titled after the requested page
+func discoverResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"page":1,"results":[{"id":603,"title":"Page %s"}],"total_pages":1,"total_results":1}`,
+		r.URL.Query().Get("page"))
+}
+
+func TestTMDBCacheHit(t *testing.T) {
+	cache := NewTMDBCache(testRedis(t), TMDBCacheConfig{})
+	params := testCacheParams(t, cache, "/movie/603")
+	ctx := context.Background()
+
+	var calls int
+	fetch := func(context.Context) ([]byte, error) {
+		calls++
+		return []byte(`{"id":603}`), nil
+	}
+
+	for i := 0; i < 2; i++ {
+		body, err := cache.Fetch(ctx, "/movie/603", params, fetch)
+		if err != nil {
+			t.Fatalf("Fetch %d: %v", i, err)
+		}
+		if string(body) != `{"id":603}` {
+			t.Fatalf("Fetch %d returned %q", i, body)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("fetched upstream %d times, want 1", calls)
+	}
+	if got, want := cache.Stats(), (TMDBCacheStats{Hits: 1, Misses: 1}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestTMDBCacheTTLPerPrefix(t *testing.T) {
+	const staleTTL = 10 * time.Minute
+	cache := NewTMDBCache(testRedis(t), TMDBCacheConfig{
+		TTLs: []TMDBCacheTTL{
+			{Prefix: "/discover/", TTL: time.Minute},
+			{Prefix: "/movie/", TTL: time.Hour},
+		},
+		DefaultTTL: 2 * time.Hour,
+		StaleTTL:   staleTTL,
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		endpoint string
+		ttl      time.Duration
+	}{
+		{"/discover/movie", time.Minute},
+		{"/movie/603", time.Hour},
+		{"/genre/movie/list", 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			if got := cache.ttlFor(tt.endpoint); got != tt.ttl {
+				t.Errorf("ttlFor(%q) = %v, want %v", tt.endpoint, got, tt.ttl)
+			}
+
+			params := testCacheParams(t, cache, tt.endpoint)
+			_, err := cache.Fetch(ctx, tt.endpoint, params, func(context.Context) ([]byte, error) {
+				return []byte(`{}`), nil
+			})
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+
+			// Entries outlive their TTL by the stale window
+			expiry, err := cache.redis.PTTL(ctx, cache.key(requestKey(tt.endpoint, params))).Result()
+			if err != nil {
+				t.Fatalf("PTTL: %v", err)
+			}
+			if want := tt.ttl + staleTTL; expiry > want || expiry < want-5*time.Second {
+				t.Errorf("entry expires in %v, want %v", expiry, want)
+			}
+		})
+	}
+}
+
+func TestTMDBCacheStaleRefreshesOnce(t *testing.T) {
+	cache := NewTMDBCache(testRedis(t), TMDBCacheConfig{
+		TTLs: []TMDBCacheTTL{{Prefix: "/discover/", TTL: time.Minute}},
+	})
+	params := testCacheParams(t, cache, "/discover/movie")
+	key := cache.key(requestKey("/discover/movie", params))
+	ctx := context.Background()
+
+	// An entry past its TTL but inside the stale window
+	stale, err := json.Marshal(tmdbCacheEntry{Body: []byte(`"stale"`), FetchedAt: time.Now().Add(-2 * time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.redis.Set(ctx, key, stale, time.Hour).Err(); err != nil {
+		t.Fatalf("failed to seed cache: %v", err)
+	}
+
+	var calls atomic.Int64
+	release := make(chan struct{})
+	fetch := func(context.Context) ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return []byte(`"fresh"`), nil
+	}
+
+	// Every reader gets the stale body straight away while one refresh is held
+	const readers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, err := cache.Fetch(ctx, "/discover/movie", params, fetch)
+			if err != nil {
+				t.Errorf("Fetch: %v", err)
+				return
+			}
+			if string(body) != `"stale"` {
+				t.Errorf("Fetch returned %s, want the stale body", body)
+			}
+		}()
+	}
+	wg.Wait()
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		entry, err := cache.get(ctx, key)
+		if err == nil && string(entry.Body) == `"fresh"` {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stale entry was not refreshed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("refreshed %d times, want 1", got)
+	}
+	if got := cache.Stats().Stale; got != readers {
+		t.Errorf("Stats().Stale = %d, want %d", got, readers)
+	}
+}
+
+func TestTMDBServiceCacheSwitch(t *testing.T) {
+	client := testRedis(t)
+	srv, hits := newFakeTMDB(t, discoverResponse)
+	ctx := context.Background()
+
+	// A page no other run asks for, so the cached service starts with a miss
+	page := 1000 + rand.IntN(1_000_000)
+	params := map[string]string{"page": fmt.Sprint(page), "sort_by": "popularity.desc"}
+
+	tests := []struct {
+		name     string
+		cache    *TMDBCache
+		wantHits int64
+	}{
+		{"disabled", nil, 2},
+		{"enabled", NewTMDBCache(client, TMDBCacheConfig{}), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.cache != nil {
+				t.Cleanup(func() {
+					client.Del(context.Background(), tt.cache.key(requestKey("/discover/movie", params)))
+				})
+			}
+			hits.Store(0)
+			svc := NewTMDBService(TMDBConfig{BaseURL: srv.URL, Cache: tt.cache})
+
+			for i := 0; i < 2; i++ {
+				if _, err := svc.DiscoverMovies(ctx, page); err != nil {
+					t.Fatalf("DiscoverMovies: %v", err)
+				}
+			}
+
+			if got := hits.Load(); got != tt.wantHits {
+				t.Errorf("upstream hits = %d, want %d", got, tt.wantHits)
+			}
+			if got := svc.CacheStats() != nil; got != (tt.cache != nil) {
+				t.Errorf("CacheStats() reported = %v, want %v", got, tt.cache != nil)
+			}
+		})
+	}
+}
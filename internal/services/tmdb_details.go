@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"fmt"
+)
+
+// topCastLimit is how many cast members are kept in movie details
+const topCastLimit = 10
+
+// TMDBGenre represents a TMDB genre
+type TMDBGenre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// TMDBCastMember represents an actor credited on a movie
+type TMDBCastMember struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Character   string  `json:"character"`
+	ProfilePath *string `json:"profile_path"`
+	Order       int     `json:"order"`
+}
+
+// TMDBCrewMember represents a crew member credited on a movie
+type TMDBCrewMember struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Job         string  `json:"job"`
+	Department  string  `json:"department"`
+	ProfilePath *string `json:"profile_path"`
+}
+
+// TMDBMovieDetails represents a movie with runtime, genres, credits and certifications
+type TMDBMovieDetails struct {
+	TMDBMovie
+	Runtime          int               `json:"runtime"`
+	Genres           []TMDBGenre       `json:"genres"`
+	Tagline          string            `json:"tagline"`
+	OriginalLanguage string            `json:"original_language"`
+	IMDbID           string            `json:"imdb_id"`
+	Cast             []TMDBCastMember  `json:"cast"`
+	Directors        []TMDBCrewMember  `json:"directors"`
+	Writers          []TMDBCrewMember  `json:"writers"`
+	Certifications   map[string]string `json:"certifications"`
+}
+
+// tmdbMovieDetailsResponse is the raw /movie/{id} response with appended credits,
+// release dates and external IDs
+type tmdbMovieDetailsResponse struct {
+	TMDBMovie
+	Runtime          int         `json:"runtime"`
+	Genres           []TMDBGenre `json:"genres"`
+	Tagline          string      `json:"tagline"`
+	OriginalLanguage string      `json:"original_language"`
+	Credits          struct {
+		Cast []TMDBCastMember `json:"cast"`
+		Crew []TMDBCrewMember `json:"crew"`
+	} `json:"credits"`
+	ReleaseDates struct {
+		Results []struct {
+			ISO3166_1    string            `json:"iso_3166_1"`
+			ReleaseDates []tmdbReleaseDate `json:"release_dates"`
+		} `json:"results"`
+	} `json:"release_dates"`
+	ExternalIDs struct {
+		IMDbID *string `json:"imdb_id"`
+	} `json:"external_ids"`
+}
+
+// tmdbReleaseDate is one release of a movie in a country
+type tmdbReleaseDate struct {
+	Certification string `json:"certification"`
+	Type          int    `json:"type"`
+}
+
+// tmdbReleaseTheatrical is the release type of a movie's theatrical release
+const tmdbReleaseTheatrical = 3
+
+// GetMovieDetails retrieves a movie with runtime, genres, credits, certifications and
+// external IDs in a single request
+func (s *TMDBService) GetMovieDetails(ctx context.Context, movieID int) (*TMDBMovieDetails, error) {
+	endpoint := fmt.Sprintf("/movie/%d", movieID)
+	params := map[string]string{
+		"append_to_response": "credits,release_dates,external_ids",
+	}
+
+	raw, err := getJSON[tmdbMovieDetailsResponse](ctx, s, endpoint, params, "movie details")
+	if err != nil {
+		return nil, err
+	}
+
+	details := &TMDBMovieDetails{
+		TMDBMovie:        raw.TMDBMovie,
+		Runtime:          raw.Runtime,
+		Genres:           raw.Genres,
+		Tagline:          raw.Tagline,
+		OriginalLanguage: raw.OriginalLanguage,
+		Cast:             raw.Credits.Cast,
+		Directors:        []TMDBCrewMember{},
+		Writers:          []TMDBCrewMember{},
+		Certifications:   make(map[string]string),
+	}
+
+	if raw.ExternalIDs.IMDbID != nil {
+		details.IMDbID = *raw.ExternalIDs.IMDbID
+	}
+
+	// Credits are already ordered by billing, keep the top of the list
+	if len(details.Cast) > topCastLimit {
+		details.Cast = details.Cast[:topCastLimit]
+	}
+
+	for _, member := range raw.Credits.Crew {
+		switch {
+		case member.Job == "Director":
+			details.Directors = append(details.Directors, member)
+		case member.Department == "Writing":
+			details.Writers = append(details.Writers, member)
+		}
+	}
+
+	for _, country := range raw.ReleaseDates.Results {
+		if certification := releaseCertification(country.ReleaseDates); certification != "" {
+			details.Certifications[country.ISO3166_1] = certification
+		}
+	}
+
+	return details, nil
+}
+
+// releaseCertification picks a country's certification, preferring the theatrical
+// release's over premieres, festival cuts and home releases, which can be rated
+// differently
+func releaseCertification(releases []tmdbReleaseDate) string {
+	fallback := ""
+	for _, release := range releases {
+		if release.Certification == "" {
+			continue
+		}
+		if release.Type == tmdbReleaseTheatrical {
+			return release.Certification
+		}
+		if fallback == "" {
+			fallback = release.Certification
+		}
+	}
+	return fallback
+}
+
+// TMDBNetwork represents a TV network
+type TMDBNetwork struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	LogoPath      *string `json:"logo_path"`
+	OriginCountry string  `json:"origin_country"`
+}
+
+// TMDBEpisode represents a single TV episode
+type TMDBEpisode struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Overview      string  `json:"overview"`
+	SeasonNumber  int     `json:"season_number"`
+	EpisodeNumber int     `json:"episode_number"`
+	AirDate       string  `json:"air_date"`
+	Runtime       *int    `json:"runtime"`
+	StillPath     *string `json:"still_path"`
+	VoteAverage   float64 `json:"vote_average"`
+}
+
+// TMDBSeasonSummary represents a season as listed on the TV details response
+type TMDBSeasonSummary struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	SeasonNumber int     `json:"season_number"`
+	EpisodeCount int     `json:"episode_count"`
+	AirDate      string  `json:"air_date"`
+	PosterPath   *string `json:"poster_path"`
+}
+
+// TMDBTVDetails represents a TV series with seasons, networks and airing status
+type TMDBTVDetails struct {
+	TMDBTV
+	NumberOfSeasons  int                 `json:"number_of_seasons"`
+	NumberOfEpisodes int                 `json:"number_of_episodes"`
+	Status           string              `json:"status"`
+	InProduction     bool                `json:"in_production"`
+	Genres           []TMDBGenre         `json:"genres"`
+	Networks         []TMDBNetwork       `json:"networks"`
+	EpisodeRunTime   []int               `json:"episode_run_time"`
+	LastEpisodeToAir *TMDBEpisode        `json:"last_episode_to_air"`
+	NextEpisodeToAir *TMDBEpisode        `json:"next_episode_to_air"`
+	Seasons          []TMDBSeasonSummary `json:"seasons"`
+}
+
+// TMDBSeason represents a TV season with its episodes
+type TMDBSeason struct {
+	ID           int           `json:"id"`
+	Name         string        `json:"name"`
+	Overview     string        `json:"overview"`
+	SeasonNumber int           `json:"season_number"`
+	AirDate      string        `json:"air_date"`
+	PosterPath   *string       `json:"poster_path"`
+	Episodes     []TMDBEpisode `json:"episodes"`
+}
+
+// GetTVDetails retrieves a TV series with seasons, networks and next/last episode to air
+func (s *TMDBService) GetTVDetails(ctx context.Context, tvID int) (*TMDBTVDetails, error) {
+	endpoint := fmt.Sprintf("/tv/%d", tvID)
+
+	return getJSON[TMDBTVDetails](ctx, s, endpoint, nil, "TV details")
+}
+
+// GetSeason retrieves a TV season with per-episode air dates and runtimes
+func (s *TMDBService) GetSeason(ctx context.Context, tvID, seasonNumber int) (*TMDBSeason, error) {
+	endpoint := fmt.Sprintf("/tv/%d/season/%d", tvID, seasonNumber)
+
+	return getJSON[TMDBSeason](ctx, s, endpoint, nil, "season")
+}
+
+// GetEpisode retrieves a single TV episode
+func (s *TMDBService) GetEpisode(ctx context.Context, tvID, seasonNumber, episodeNumber int) (*TMDBEpisode, error) {
+	endpoint := fmt.Sprintf("/tv/%d/season/%d/episode/%d", tvID, seasonNumber, episodeNumber)
+
+	return getJSON[TMDBEpisode](ctx, s, endpoint, nil, "episode")
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestGetMovieDetailsPrefersTheatricalCertification(t *testing.T) {
+	srv, _ := newFakeTMDB(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":603,"title":"The Matrix","release_dates":{"results":[
+			{"iso_3166_1":"US","release_dates":[
+				{"certification":"NR","type":1},
+				{"certification":"","type":2},
+				{"certification":"R","type":3},
+				{"certification":"PG-13","type":6}
+			]},
+			{"iso_3166_1":"DE","release_dates":[
+				{"certification":"","type":3},
+				{"certification":"16","type":5},
+				{"certification":"18","type":4}
+			]},
+			{"iso_3166_1":"FR","release_dates":[{"certification":"","type":3}]}
+		]}}`))
+	})
+	svc := NewTMDBService(TMDBConfig{BaseURL: srv.URL})
+
+	details, err := svc.GetMovieDetails(context.Background(), 603)
+	if err != nil {
+		t.Fatalf("GetMovieDetails: %v", err)
+	}
+
+	// Countries without a rated theatrical release fall back to their first rating
+	want := map[string]string{"US": "R", "DE": "16"}
+	if !reflect.DeepEqual(details.Certifications, want) {
+		t.Errorf("Certifications = %v, want %v", details.Certifications, want)
+	}
+}
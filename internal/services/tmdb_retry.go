@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/liamwears/reelscore/internal/apperror"
+)
+
+// TMDB errors are application errors, so handlers report them as not found or
+// upstream unavailable
+var (
+	// ErrTMDBNotFound is returned when TMDB responds with 404
+	ErrTMDBNotFound = apperror.NotFound("TMDB resource not found")
+	// ErrTMDBRateLimited is returned when TMDB keeps responding with 429
+	ErrTMDBRateLimited = apperror.UpstreamUnavailable("TMDB rate limit exceeded", nil)
+	// ErrTMDBUnavailable is returned when TMDB cannot be reached or keeps failing with 5xx
+	ErrTMDBUnavailable = apperror.UpstreamUnavailable("TMDB unavailable", nil)
+)
+
+// TMDBRetryConfig holds retry and client-side rate limit settings
+type TMDBRetryConfig struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// MaxRetryAfter caps how long a 429 Retry-After is honored before giving up
+	MaxRetryAfter time.Duration
+	// RequestsPerSecond is the client-side token bucket rate
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// withDefaults fills in zero values
+func (c TMDBRetryConfig) withDefaults() TMDBRetryConfig {
+	if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+	if c.BaseDelay == 0 {
+		c.BaseDelay = 200 * time.Millisecond
+	}
+	if c.MaxDelay == 0 {
+		c.MaxDelay = 5 * time.Second
+	}
+	if c.MaxRetryAfter == 0 {
+		c.MaxRetryAfter = 10 * time.Second
+	}
+	if c.RequestsPerSecond == 0 {
+		c.RequestsPerSecond = 40 // TMDB allows roughly 50 req/s per IP
+	}
+	if c.Burst == 0 {
+		c.Burst = 20
+	}
+	return c
+}
+
+// backoff returns the jittered exponential delay before the given retry attempt
+func (c TMDBRetryConfig) backoff(attempt int) time.Duration {
+	delay := c.BaseDelay << attempt
+	if delay <= 0 || delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	// Full jitter keeps concurrent retries from lining up
+	return time.Duration(rand.Int64N(int64(delay)) + 1)
+}
+
+// parseRetryAfter parses a Retry-After header in seconds or HTTP-date form
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// tokenBucket is a simple client-side rate limiter
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full token bucket
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long the caller must wait before using it
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Wait blocks until a token is available or ctx is done
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	wait := b.reserve()
+	if wait == 0 {
+		return nil
+	}
+	return sleepContext(ctx, wait)
+}
@@ -3,10 +3,22 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"time"
+
+	"github.com/liamwears/reelscore/internal/metrics"
+	"github.com/liamwears/reelscore/internal/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 )
 
 // TMDBService handles interactions with The Movie Database API
@@ -15,6 +27,10 @@ type TMDBService struct {
 	apiKey       string
 	baseURL      string
 	imageBaseURL string
+	cache        *TMDBCache
+	inflight     singleflight.Group
+	retry        TMDBRetryConfig
+	limiter      *tokenBucket
 }
 
 // TMDBConfig holds TMDB service configuration
@@ -22,17 +38,29 @@ type TMDBConfig struct {
 	APIKey       string
 	BaseURL      string
 	ImageBaseURL string
+	// Cache is optional, responses are fetched directly when nil
+	Cache *TMDBCache
+	Retry TMDBRetryConfig
 }
 
 // NewTMDBService creates a new TMDB service
 func NewTMDBService(cfg TMDBConfig) *TMDBService {
+	retry := cfg.Retry.withDefaults()
 	return &TMDBService{
 		client: &http.Client{
 			Timeout: 10 * time.Second,
+			// Each attempt gets a client span, so retries and cache misses show up
+			// under doRequest's span. Trace context isn't sent to a third party.
+			Transport: otelhttp.NewTransport(http.DefaultTransport,
+				otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator()),
+			),
 		},
 		apiKey:       cfg.APIKey,
 		baseURL:      cfg.BaseURL,
 		imageBaseURL: cfg.ImageBaseURL,
+		cache:        cfg.Cache,
+		retry:        retry,
+		limiter:      newTokenBucket(retry.RequestsPerSecond, retry.Burst),
 	}
 }
 
@@ -84,13 +112,137 @@ type TMDBTVResponse struct {
 	TotalResults int      `json:"total_results"`
 }
 
-// doRequest performs an HTTP request to TMDB API
+// doRequest performs an HTTP request to TMDB API, going through the cache when enabled
 func (s *TMDBService) doRequest(ctx context.Context, endpoint string, params map[string]string) ([]byte, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "TMDB "+metrics.TMDBEndpoint(endpoint),
+		trace.WithAttributes(
+			attribute.String("tmdb.endpoint", endpoint),
+			attribute.Bool("tmdb.cache_enabled", s.cache != nil),
+		),
+	)
+	defer span.End()
+
+	var body []byte
+	var err error
+	if s.cache == nil {
+		body, err = s.fetch(ctx, endpoint, params)
+	} else {
+		body, err = s.cache.Fetch(ctx, endpoint, params, func(ctx context.Context) ([]byte, error) {
+			return s.fetch(ctx, endpoint, params)
+		})
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return body, err
+}
+
+// CacheStats returns the response cache counters, or nil when caching is disabled
+func (s *TMDBService) CacheStats() *TMDBCacheStats {
+	if s.cache == nil {
+		return nil
+	}
+	stats := s.cache.Stats()
+	return &stats
+}
+
+// requestKey builds a stable key from the endpoint and params, escaped and sorted so
+// that distinct requests never share a key
+func requestKey(endpoint string, params map[string]string) string {
+	values := make(url.Values, len(params))
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	return endpoint + "?" + values.Encode()
+}
+
+// coalesce runs fn once for all concurrent callers sharing the same key. The shared
+// call is detached from any single caller's cancellation so one caller giving up does
+// not fail the others; each caller still stops waiting when its own context is done.
+func coalesce[T any](ctx context.Context, s *TMDBService, key string, fn func(context.Context) (T, error)) (T, error) {
+	ch := s.inflight.DoChan(key, func() (interface{}, error) {
+		return fn(context.WithoutCancel(ctx))
+	})
+
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			var zero T
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	}
+}
+
+// getJSON fetches an endpoint and decodes it into T. Concurrent identical requests
+// share one upstream call and one decoded result, so callers must not modify it.
+func getJSON[T any](ctx context.Context, s *TMDBService, endpoint string, params map[string]string, what string) (*T, error) {
+	key := fmt.Sprintf("%T %s", *new(T), requestKey(endpoint, params))
+
+	return coalesce(ctx, s, key, func(ctx context.Context) (*T, error) {
+		body, err := s.doRequest(ctx, endpoint, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var result T
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", what, err)
+		}
+
+		return &result, nil
+	})
+}
+
+// fetch performs the upstream HTTP request to TMDB API, retrying transient failures
+func (s *TMDBService) fetch(ctx context.Context, endpoint string, params map[string]string) ([]byte, error) {
+	var lastErr error
+
+	for attempt := 0; ; attempt++ {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		body, retryAfter, err := s.fetchOnce(ctx, endpoint, params)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+
+		// Only rate limits and unavailability are worth retrying
+		if !errors.Is(err, ErrTMDBRateLimited) && !errors.Is(err, ErrTMDBUnavailable) {
+			return nil, err
+		}
+		if ctx.Err() != nil || attempt >= s.retry.MaxRetries {
+			return nil, lastErr
+		}
+
+		delay := s.retry.backoff(attempt)
+		if errors.Is(err, ErrTMDBRateLimited) && retryAfter > 0 {
+			if retryAfter > s.retry.MaxRetryAfter {
+				return nil, lastErr
+			}
+			delay = retryAfter
+		}
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, lastErr
+		}
+	}
+}
+
+// fetchOnce performs a single HTTP request to TMDB API. On 429 it also returns the
+// Retry-After delay requested by TMDB.
+func (s *TMDBService) fetchOnce(ctx context.Context, endpoint string, params map[string]string) ([]byte, time.Duration, error) {
 	url := fmt.Sprintf("%s%s", s.baseURL, endpoint)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Add authorization header
@@ -106,54 +258,60 @@ func (s *TMDBService) doRequest(ctx context.Context, endpoint string, params map
 	}
 	req.URL.RawQuery = q.Encode()
 
+	start := time.Now()
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		latency := time.Since(start)
+		metrics.ObserveTMDB(endpoint, 0, latency)
+		slog.WarnContext(ctx, "TMDB request failed", "endpoint", endpoint, "latency", latency, "error", err)
+		if ctx.Err() != nil {
+			return nil, 0, ctx.Err()
+		}
+		return nil, 0, fmt.Errorf("%w: failed to execute request: %v", ErrTMDBUnavailable, err)
 	}
 	defer resp.Body.Close()
 
+	// Logged with the caller's context so upstream calls share its request ID
+	level := slog.LevelInfo
+	if resp.StatusCode != http.StatusOK {
+		level = slog.LevelWarn
+	}
+	latency := time.Since(start)
+	metrics.ObserveTMDB(endpoint, resp.StatusCode, latency)
+	slog.Log(ctx, level, "TMDB request", "endpoint", endpoint, "status", resp.StatusCode, "latency", latency)
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, 0, fmt.Errorf("%w: failed to read response body: %v", ErrTMDBUnavailable, err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("TMDB API error: status %d, body: %s", resp.StatusCode, string(body))
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return body, 0, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, 0, fmt.Errorf("%w: %s", ErrTMDBNotFound, endpoint)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"))
+		return nil, retryAfter, fmt.Errorf("%w: %s", ErrTMDBRateLimited, endpoint)
+	case resp.StatusCode >= 500:
+		return nil, 0, fmt.Errorf("%w: status %d", ErrTMDBUnavailable, resp.StatusCode)
+	default:
+		return nil, 0, fmt.Errorf("TMDB API error: status %d, body: %s", resp.StatusCode, string(body))
 	}
-
-	return body, nil
 }
 
 // GetMovie retrieves a movie by ID
 func (s *TMDBService) GetMovie(ctx context.Context, movieID int) (*TMDBMovie, error) {
 	endpoint := fmt.Sprintf("/movie/%d", movieID)
-	body, err := s.doRequest(ctx, endpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	var movie TMDBMovie
-	if err := json.Unmarshal(body, &movie); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal movie: %w", err)
-	}
 
-	return &movie, nil
+	return getJSON[TMDBMovie](ctx, s, endpoint, nil, "movie")
 }
 
 // GetTV retrieves a TV series by ID
 func (s *TMDBService) GetTV(ctx context.Context, tvID int) (*TMDBTV, error) {
 	endpoint := fmt.Sprintf("/tv/%d", tvID)
-	body, err := s.doRequest(ctx, endpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	var tv TMDBTV
-	if err := json.Unmarshal(body, &tv); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal TV series: %w", err)
-	}
 
-	return &tv, nil
+	return getJSON[TMDBTV](ctx, s, endpoint, nil, "TV series")
 }
 
 // SearchMulti searches both movies and TV series
@@ -167,7 +325,9 @@ func (s *TMDBService) SearchMulti(ctx context.Context, query string, page int) (
 		"page":  fmt.Sprintf("%d", page),
 	}
 
-	return s.doRequest(ctx, "/search/multi", params)
+	return coalesce(ctx, s, requestKey("/search/multi", params), func(ctx context.Context) ([]byte, error) {
+		return s.doRequest(ctx, "/search/multi", params)
+	})
 }
 
 // SearchMovies searches for movies
@@ -181,17 +341,7 @@ func (s *TMDBService) SearchMovies(ctx context.Context, query string, page int)
 		"page":  fmt.Sprintf("%d", page),
 	}
 
-	body, err := s.doRequest(ctx, "/search/movie", params)
-	if err != nil {
-		return nil, err
-	}
-
-	var response TMDBMovieResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal search results: %w", err)
-	}
-
-	return &response, nil
+	return getJSON[TMDBMovieResponse](ctx, s, "/search/movie", params, "search results")
 }
 
 // SearchTV searches for TV series
@@ -205,17 +355,7 @@ func (s *TMDBService) SearchTV(ctx context.Context, query string, page int) (*TM
 		"page":  fmt.Sprintf("%d", page),
 	}
 
-	body, err := s.doRequest(ctx, "/search/tv", params)
-	if err != nil {
-		return nil, err
-	}
-
-	var response TMDBTVResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal search results: %w", err)
-	}
-
-	return &response, nil
+	return getJSON[TMDBTVResponse](ctx, s, "/search/tv", params, "search results")
 }
 
 // DiscoverMovies gets popular/discover movies
@@ -229,17 +369,7 @@ func (s *TMDBService) DiscoverMovies(ctx context.Context, page int) (*TMDBMovieR
 		"sort_by": "popularity.desc",
 	}
 
-	body, err := s.doRequest(ctx, "/discover/movie", params)
-	if err != nil {
-		return nil, err
-	}
-
-	var response TMDBMovieResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal discover results: %w", err)
-	}
-
-	return &response, nil
+	return getJSON[TMDBMovieResponse](ctx, s, "/discover/movie", params, "discover results")
 }
 
 // DiscoverTV gets popular/discover TV series
@@ -253,17 +383,7 @@ func (s *TMDBService) DiscoverTV(ctx context.Context, page int) (*TMDBTVResponse
 		"sort_by": "popularity.desc",
 	}
 
-	body, err := s.doRequest(ctx, "/discover/tv", params)
-	if err != nil {
-		return nil, err
-	}
-
-	var response TMDBTVResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal discover results: %w", err)
-	}
-
-	return &response, nil
+	return getJSON[TMDBTVResponse](ctx, s, "/discover/tv", params, "discover results")
 }
 
 // GetImageURL returns the full URL for an image path
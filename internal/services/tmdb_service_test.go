@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTMDBServiceCoalescesIdenticalRequests(t *testing.T) {
+	const callers = 20
+
+	release := make(chan struct{})
+	srv, hits := newFakeTMDB(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		discoverResponse(w, r)
+	})
+	svc := NewTMDBService(TMDBConfig{BaseURL: srv.URL})
+	ctx := context.Background()
+
+	results := make([]*TMDBMovieResponse, callers)
+	var started, done sync.WaitGroup
+	started.Add(callers)
+	done.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+			res, err := svc.DiscoverMovies(ctx, 1)
+			if err != nil {
+				t.Errorf("DiscoverMovies: %v", err)
+				return
+			}
+			results[i] = res
+		}()
+	}
+
+	// Hold the upstream response until every caller has joined the shared request
+	started.Wait()
+	waitFor(t, func() bool { return hits.Load() > 0 })
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	done.Wait()
+
+	if got := hits.Load(); got != 1 {
+		t.Errorf("upstream hits = %d, want 1", got)
+	}
+	for i, res := range results {
+		if res != results[0] {
+			t.Fatalf("caller %d got a different result than caller 0", i)
+		}
+	}
+	if results[0] == nil || len(results[0].Results) != 1 || results[0].Results[0].Title != "Page 1" {
+		t.Errorf("unexpected result %+v", results[0])
+	}
+}
+
+func TestTMDBServiceDoesNotCoalesceDifferentRequests(t *testing.T) {
+	// Both requests must be upstream at once, proving neither waited on the other
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	srv, hits := newFakeTMDB(t, func(w http.ResponseWriter, r *http.Request) {
+		arrived.Done()
+		arrived.Wait()
+		discoverResponse(w, r)
+	})
+	svc := NewTMDBService(TMDBConfig{BaseURL: srv.URL})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	titles := make([]string, 2)
+	var done sync.WaitGroup
+	for i := range titles {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			res, err := svc.DiscoverMovies(ctx, i+1)
+			if err != nil {
+				t.Errorf("DiscoverMovies(%d): %v", i+1, err)
+				return
+			}
+			titles[i] = res.Results[0].Title
+		}()
+	}
+	done.Wait()
+
+	if got := hits.Load(); got != 2 {
+		t.Errorf("upstream hits = %d, want 2", got)
+	}
+	if titles[0] != "Page 1" || titles[1] != "Page 2" {
+		t.Errorf("titles = %q, want each page's own result", titles)
+	}
+}
+
+func TestRequestKeyDoesNotCollide(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b map[string]string
+	}{
+		{"ampersand in a value", map[string]string{"query": "a&page=2", "page": "1"}, map[string]string{"query": "a", "page": "2"}},
+		{"equals sign in a value", map[string]string{"query": "a=b"}, map[string]string{"query=a": "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := requestKey("/search/multi", tt.a), requestKey("/search/multi", tt.b)
+			if a == b {
+				t.Errorf("requestKey() = %q for both %v and %v", a, tt.a, tt.b)
+			}
+		})
+	}
+
+	// Param order doesn't change the key
+	if requestKey("/discover/movie", map[string]string{"page": "1", "sort_by": "popularity.desc"}) !=
+		requestKey("/discover/movie", map[string]string{"sort_by": "popularity.desc", "page": "1"}) {
+		t.Error("requestKey() depends on param order")
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -10,6 +12,17 @@ import (
 	"github.com/liamwears/reelscore/internal/models"
 )
 
+var (
+	// ErrIdentityInUse is returned when linking an identity that belongs to another user
+	ErrIdentityInUse = errors.New("identity is linked to another account")
+	// ErrLastIdentity is returned when unlinking a user's only identity
+	ErrLastIdentity = errors.New("cannot unlink the last login provider")
+)
+
+// AccountDeletionGracePeriod is how long a deletion request can be cancelled for
+// before the account and its data are removed
+const AccountDeletionGracePeriod = 14 * 24 * time.Hour
+
 // UserService handles user-related business logic
 type UserService struct {
 	db *pgxpool.Pool
@@ -20,10 +33,10 @@ func NewUserService(db *pgxpool.Pool) *UserService {
 	return &UserService{db: db}
 }
 
-// FindOrCreate finds a user by provider ID or creates a new one
+// FindOrCreate finds a user by a linked provider identity or creates a new one
 func (s *UserService) FindOrCreate(ctx context.Context, providerID string, provider models.Provider, email, name string) (*models.User, error) {
 	// Try to find existing user
-	user, err := s.FindByProviderID(ctx, providerID)
+	user, err := s.FindByIdentity(ctx, provider, providerID)
 	if err == nil {
 		return user, nil
 	}
@@ -36,23 +49,50 @@ func (s *UserService) FindOrCreate(ctx context.Context, providerID string, provi
 	return nil, fmt.Errorf("failed to find user: %w", err)
 }
 
-// FindByProviderID finds a user by their provider ID
-func (s *UserService) FindByProviderID(ctx context.Context, providerID string) (*models.User, error) {
+// FindByIdentity finds a user by a linked provider identity
+func (s *UserService) FindByIdentity(ctx context.Context, provider models.Provider, providerID string) (*models.User, error) {
 	query := `
-		SELECT id, "providerId", provider, email, name, "createdAt", "updatedAt"
+		SELECT u.id, u.email, u.name, u."createdAt", u."updatedAt", u."deletionScheduledAt"
+		FROM "User" u
+		JOIN "UserIdentity" i ON i."userId" = u.id
+		WHERE i.provider = $1 AND i."providerId" = $2
+	`
+
+	var user models.User
+	err := s.db.QueryRow(ctx, query, provider, providerID).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Name,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DeletionScheduledAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// FindByEmail finds a user by their email address
+func (s *UserService) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := `
+		SELECT id, email, name, "createdAt", "updatedAt", "deletionScheduledAt"
 		FROM "User"
-		WHERE "providerId" = $1
+		WHERE email = $1
+		ORDER BY "createdAt" ASC
+		LIMIT 1
 	`
 
 	var user models.User
-	err := s.db.QueryRow(ctx, query, providerID).Scan(
+	err := s.db.QueryRow(ctx, query, email).Scan(
 		&user.ID,
-		&user.ProviderID,
-		&user.Provider,
 		&user.Email,
 		&user.Name,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeletionScheduledAt,
 	)
 
 	if err != nil {
@@ -62,40 +102,56 @@ func (s *UserService) FindByProviderID(ctx context.Context, providerID string) (
 	return &user, nil
 }
 
-// Create creates a new user
+// Create creates a new user with their first identity
 func (s *UserService) Create(ctx context.Context, providerID string, provider models.Provider, email, name string) (*models.User, error) {
 	if !provider.IsValid() {
 		return nil, fmt.Errorf("invalid provider: %s", provider)
 	}
 
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		INSERT INTO "User" ("providerId", provider, email, name)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, "providerId", provider, email, name, "createdAt", "updatedAt"
+		INSERT INTO "User" (email, name)
+		VALUES ($1, $2)
+		RETURNING id, email, name, "createdAt", "updatedAt", "deletionScheduledAt"
 	`
 
 	var user models.User
-	err := s.db.QueryRow(ctx, query, providerID, provider, email, name).Scan(
+	err = tx.QueryRow(ctx, query, email, name).Scan(
 		&user.ID,
-		&user.ProviderID,
-		&user.Provider,
 		&user.Email,
 		&user.Name,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeletionScheduledAt,
 	)
-
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	_, err = tx.Exec(ctx, `
+		INSERT INTO "UserIdentity" ("userId", provider, "providerId", email)
+		VALUES ($1, $2, $3, $4)
+	`, user.ID, provider, providerID, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create identity: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return &user, nil
 }
 
 // Get retrieves a user by ID
 func (s *UserService) Get(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	query := `
-		SELECT id, "providerId", provider, email, name, "createdAt", "updatedAt"
+		SELECT id, email, name, "createdAt", "updatedAt", "deletionScheduledAt"
 		FROM "User"
 		WHERE id = $1
 	`
@@ -103,12 +159,11 @@ func (s *UserService) Get(ctx context.Context, id uuid.UUID) (*models.User, erro
 	var user models.User
 	err := s.db.QueryRow(ctx, query, id).Scan(
 		&user.ID,
-		&user.ProviderID,
-		&user.Provider,
 		&user.Email,
 		&user.Name,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeletionScheduledAt,
 	)
 
 	if err != nil {
@@ -121,7 +176,7 @@ func (s *UserService) Get(ctx context.Context, id uuid.UUID) (*models.User, erro
 // GetAll retrieves all users (mainly for admin purposes)
 func (s *UserService) GetAll(ctx context.Context) ([]*models.User, error) {
 	query := `
-		SELECT id, "providerId", provider, email, name, "createdAt", "updatedAt"
+		SELECT id, email, name, "createdAt", "updatedAt", "deletionScheduledAt"
 		FROM "User"
 		ORDER BY "createdAt" DESC
 	`
@@ -137,12 +192,11 @@ func (s *UserService) GetAll(ctx context.Context) ([]*models.User, error) {
 		var user models.User
 		err := rows.Scan(
 			&user.ID,
-			&user.ProviderID,
-			&user.Provider,
 			&user.Email,
 			&user.Name,
 			&user.CreatedAt,
 			&user.UpdatedAt,
+			&user.DeletionScheduledAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
@@ -163,18 +217,17 @@ func (s *UserService) Update(ctx context.Context, id uuid.UUID, email, name stri
 		UPDATE "User"
 		SET email = $2, name = $3, "updatedAt" = NOW()
 		WHERE id = $1
-		RETURNING id, "providerId", provider, email, name, "createdAt", "updatedAt"
+		RETURNING id, email, name, "createdAt", "updatedAt", "deletionScheduledAt"
 	`
 
 	var user models.User
 	err := s.db.QueryRow(ctx, query, id, email, name).Scan(
 		&user.ID,
-		&user.ProviderID,
-		&user.Provider,
 		&user.Email,
 		&user.Name,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeletionScheduledAt,
 	)
 
 	if err != nil {
@@ -199,3 +252,187 @@ func (s *UserService) Delete(ctx context.Context, id uuid.UUID) error {
 
 	return nil
 }
+
+// DeleteIfDue deletes a user whose deletion grace period has passed. It reports false
+// when the user no longer exists or cancelled the deletion in the meantime.
+func (s *UserService) DeleteIfDue(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `DELETE FROM "User" WHERE id = $1 AND "deletionScheduledAt" <= NOW()`
+
+	result, err := s.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// ScheduleDeletion marks a user's account for deletion once the grace period ends.
+// Asking again keeps the original date.
+func (s *UserService) ScheduleDeletion(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	query := `
+		UPDATE "User"
+		SET "deletionScheduledAt" = COALESCE("deletionScheduledAt", NOW() + $2 * INTERVAL '1 second'), "updatedAt" = NOW()
+		WHERE id = $1
+		RETURNING id, email, name, "createdAt", "updatedAt", "deletionScheduledAt"
+	`
+
+	var user models.User
+	err := s.db.QueryRow(ctx, query, id, AccountDeletionGracePeriod.Seconds()).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Name,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DeletionScheduledAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule deletion: %w", err)
+	}
+
+	return &user, nil
+}
+
+// CancelDeletion clears a pending account deletion
+func (s *UserService) CancelDeletion(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE "User" SET "deletionScheduledAt" = NULL, "updatedAt" = NOW() WHERE id = $1`
+
+	result, err := s.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to cancel deletion: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// DueForDeletion lists the users whose deletion grace period has ended
+func (s *UserService) DueForDeletion(ctx context.Context) ([]uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM "User"
+		WHERE "deletionScheduledAt" <= NOW()
+		ORDER BY "deletionScheduledAt" ASC
+	`
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users due for deletion: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return ids, nil
+}
+
+// Identities lists the provider identities linked to a user, oldest first
+func (s *UserService) Identities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	query := `
+		SELECT id, "userId", provider, "providerId", email, "createdAt"
+		FROM "UserIdentity"
+		WHERE "userId" = $1
+		ORDER BY "createdAt" ASC
+	`
+
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []models.UserIdentity{}
+	for rows.Next() {
+		var identity models.UserIdentity
+		err := rows.Scan(
+			&identity.ID,
+			&identity.UserID,
+			&identity.Provider,
+			&identity.ProviderID,
+			&identity.Email,
+			&identity.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+// LinkIdentity links a provider identity to a user. Linking an identity the user
+// already has is a no-op.
+func (s *UserService) LinkIdentity(ctx context.Context, userID uuid.UUID, providerID string, provider models.Provider, email string) error {
+	if !provider.IsValid() {
+		return fmt.Errorf("invalid provider: %s", provider)
+	}
+
+	var ownerID uuid.UUID
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO "UserIdentity" ("userId", provider, "providerId", email)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (provider, "providerId") DO UPDATE SET email = "UserIdentity".email
+		RETURNING "userId"
+	`, userID, provider, providerID, email).Scan(&ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	if ownerID != userID {
+		return ErrIdentityInUse
+	}
+
+	return nil
+}
+
+// UnlinkIdentity removes a provider identity from a user, refusing to remove the
+// last one so the account stays reachable
+func (s *UserService) UnlinkIdentity(ctx context.Context, userID, identityID uuid.UUID) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the user so concurrent unlinks can't remove every identity
+	if _, err := tx.Exec(ctx, `SELECT id FROM "User" WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM "UserIdentity" WHERE "userId" = $1`, userID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count identities: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM "UserIdentity" WHERE id = $1 AND "userId" = $2`, identityID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unlink identity: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	if count <= 1 {
+		return ErrLastIdentity
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/liamwears/reelscore/internal/models"
+)
+
+var (
+	// ErrInvalidViewingDate is returned when a watched-on date is not YYYY-MM-DD
+	ErrInvalidViewingDate = errors.New("invalid watched-on date")
+	// ErrInvalidViewingRating is returned when a rating is outside 0-10
+	ErrInvalidViewingRating = errors.New("rating must be between 0 and 10")
+)
+
+// ViewingService handles the watch diary
+type ViewingService struct {
+	db *pgxpool.Pool
+}
+
+// NewViewingService creates a new ViewingService
+func NewViewingService(db *pgxpool.Pool) *ViewingService {
+	return &ViewingService{db: db}
+}
+
+// Create logs a viewing of a movie and marks the movie as watched
+func (s *ViewingService) Create(ctx context.Context, userID, movieID uuid.UUID, input models.CreateViewingInput) (*models.Viewing, error) {
+	// Parse watched-on date, defaulting to today
+	date := time.Now().Format("2006-01-02")
+	if input.WatchedOn != nil && *input.WatchedOn != "" {
+		date = *input.WatchedOn
+	}
+	watchedOn, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidViewingDate, err)
+	}
+	if input.Rating != nil && (*input.Rating < 0 || *input.Rating > 10) {
+		return nil, ErrInvalidViewingRating
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Mark the movie as watched, which also checks it belongs to the user
+	result, err := tx.Exec(ctx, `
+		UPDATE "Movie" SET watched = true, "updatedAt" = NOW()
+		WHERE id = $1 AND "userId" = $2
+	`, movieID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark movie as watched: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, pgx.ErrNoRows
+	}
+
+	// A viewing is a rewatch when an earlier one exists, unless told otherwise
+	rewatch := false
+	if input.Rewatch != nil {
+		rewatch = *input.Rewatch
+	} else {
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM "Viewing"
+				WHERE "movieId" = $1 AND "userId" = $2 AND "watchedOn" <= $3
+			)
+		`, movieID, userID, watchedOn).Scan(&rewatch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check earlier viewings: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO "Viewing" ("movieId", "userId", "watchedOn", rating, note, rewatch)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, "movieId", "userId", "watchedOn", rating, note, rewatch, "createdAt"
+	`
+
+	var viewing models.Viewing
+	err = tx.QueryRow(ctx, query,
+		movieID,
+		userID,
+		watchedOn,
+		input.Rating,
+		input.Note,
+		rewatch,
+	).Scan(
+		&viewing.ID,
+		&viewing.MovieID,
+		&viewing.UserID,
+		&viewing.WatchedOn,
+		&viewing.Rating,
+		&viewing.Note,
+		&viewing.Rewatch,
+		&viewing.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create viewing: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit viewing: %w", err)
+	}
+
+	return &viewing, nil
+}
+
+// List retrieves all viewings of a movie, most recent first
+func (s *ViewingService) List(ctx context.Context, userID, movieID uuid.UUID) ([]models.Viewing, error) {
+	query := `
+		SELECT id, "movieId", "userId", "watchedOn", rating, note, rewatch, "createdAt"
+		FROM "Viewing"
+		WHERE "movieId" = $1 AND "userId" = $2
+		ORDER BY "watchedOn" DESC, "createdAt" DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, movieID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query viewings: %w", err)
+	}
+	defer rows.Close()
+
+	viewings := []models.Viewing{}
+	for rows.Next() {
+		var viewing models.Viewing
+		err := rows.Scan(
+			&viewing.ID,
+			&viewing.MovieID,
+			&viewing.UserID,
+			&viewing.WatchedOn,
+			&viewing.Rating,
+			&viewing.Note,
+			&viewing.Rewatch,
+			&viewing.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan viewing: %w", err)
+		}
+		viewings = append(viewings, viewing)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating viewings: %w", err)
+	}
+
+	return viewings, nil
+}
+
+// Delete deletes a viewing of a movie
+func (s *ViewingService) Delete(ctx context.Context, userID, movieID, viewingID uuid.UUID) error {
+	query := `DELETE FROM "Viewing" WHERE id = $1 AND "movieId" = $2 AND "userId" = $3`
+
+	result, err := s.db.Exec(ctx, query, viewingID, movieID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete viewing: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// Diary retrieves all viewings in the given month, most recent first
+func (s *ViewingService) Diary(ctx context.Context, userID uuid.UUID, month time.Time) ([]models.DiaryEntry, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	query := `
+		SELECT v.id, v."movieId", v."userId", v."watchedOn", v.rating, v.note, v.rewatch, v."createdAt",
+		       m."tmdbId", m.title, m."posterPath", m."releaseDate"
+		FROM "Viewing" v
+		JOIN "Movie" m ON m.id = v."movieId"
+		WHERE v."userId" = $1 AND v."watchedOn" >= $2 AND v."watchedOn" < $3
+		ORDER BY v."watchedOn" DESC, v."createdAt" DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, userID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query diary: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.DiaryEntry
+	for rows.Next() {
+		var entry models.DiaryEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.MovieID,
+			&entry.UserID,
+			&entry.WatchedOn,
+			&entry.Rating,
+			&entry.Note,
+			&entry.Rewatch,
+			&entry.CreatedAt,
+			&entry.TmdbID,
+			&entry.Title,
+			&entry.PosterPath,
+			&entry.ReleaseDate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan diary entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating diary: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,54 @@
+// Package tracing sets up OpenTelemetry tracing. Requests, Postgres queries, Redis
+// commands and TMDB calls are traced through the global tracer provider, which does
+// nothing until SetProvider installs one.
+package tracing
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans ReelScore starts itself
+const ScopeName = "github.com/liamwears/reelscore"
+
+// ProviderConfig holds span sampling configuration
+type ProviderConfig struct {
+	// ServiceName is reported as service.name
+	ServiceName string
+	// SampleRatio is the fraction of new traces recorded, all of them when zero.
+	// Traces continued from another service keep that service's decision.
+	SampleRatio float64
+}
+
+// NewProvider creates a tracer provider exporting spans in batches from a background
+// goroutine, so that exporting never slows down the traced work
+func NewProvider(exporter sdktrace.SpanExporter, cfg ProviderConfig) *sdktrace.TracerProvider {
+	if cfg.SampleRatio <= 0 || cfg.SampleRatio > 1 {
+		cfg.SampleRatio = 1
+	}
+
+	// Merging a schemaless resource can't conflict with the default one's schema
+	res, _ := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)))
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+}
+
+// SetProvider installs the provider used by every instrumented component, along with
+// the W3C Trace Context propagator so incoming traceparent headers are honoured
+func SetProvider(provider trace.TracerProvider) {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+// Tracer returns the tracer for ReelScore's own spans
+func Tracer() trace.Tracer {
+	return otel.Tracer(ScopeName)
+}
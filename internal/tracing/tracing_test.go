@@ -0,0 +1,228 @@
+package tracing_test
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/liamwears/reelscore/internal/database"
+	"github.com/liamwears/reelscore/internal/middleware"
+	"github.com/liamwears/reelscore/internal/services"
+	"github.com/liamwears/reelscore/internal/tracing"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// incomingTraceparent is the trace a proxy in front of the server started
+const incomingTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// installExporter records every span in memory until the test ends
+func installExporter(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	previous := otel.GetTracerProvider()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracing.SetProvider(provider)
+	t.Cleanup(func() {
+		provider.Shutdown(t.Context())
+		otel.SetTracerProvider(previous)
+	})
+	return exporter
+}
+
+// serve sends a traced GET for path to a mux routing pattern to handle
+func serve(t *testing.T, pattern, path string, handle http.HandlerFunc) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.Handle(pattern, handle)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := middleware.Chain(middleware.Trace, middleware.Logger(logger), middleware.RecordRoute)(mux)
+
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.Header.Set("traceparent", incomingTraceparent)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+}
+
+// findSpan returns the span named name, failing the test when there isn't one
+func findSpan(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name
+	}
+	t.Fatalf("no span named %q in %q", name, names)
+	return tracetest.SpanStub{}
+}
+
+// assertChild checks that child was started directly under parent
+func assertChild(t *testing.T, parent, child tracetest.SpanStub) {
+	t.Helper()
+
+	if child.Parent.TraceID() != parent.SpanContext.TraceID() || child.Parent.SpanID() != parent.SpanContext.SpanID() {
+		t.Errorf("span %q has parent %s/%s, want %q (%s/%s)", child.Name,
+			child.Parent.TraceID(), child.Parent.SpanID(),
+			parent.Name, parent.SpanContext.TraceID(), parent.SpanContext.SpanID())
+	}
+}
+
+func TestRequestSpans(t *testing.T) {
+	exporter := installExporter(t)
+
+	db, err := database.New(database.Config{URL: startFakePostgres(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	tmdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"page":1,"results":[]}`)
+	}))
+	t.Cleanup(tmdb.Close)
+	tmdbService := services.NewTMDBService(services.TMDBConfig{BaseURL: tmdb.URL})
+
+	exporter.Reset()
+	serve(t, "GET /api/movies/{id}", "/api/movies/42", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := db.Exec(r.Context(), "SELECT 1"); err != nil {
+			t.Errorf("Exec: %v", err)
+		}
+		if _, err := tmdbService.DiscoverMovies(r.Context(), 1); err != nil {
+			t.Errorf("DiscoverMovies: %v", err)
+		}
+	})
+
+	spans := exporter.GetSpans()
+	server := findSpan(t, spans, "GET /api/movies/{id}")
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("request span kind = %v, want server", server.SpanKind)
+	}
+	if got := server.Parent.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("request span trace = %s, want the incoming traceparent's", got)
+	}
+
+	query := findSpan(t, spans, "SELECT")
+	assertChild(t, server, query)
+	if query.SpanKind != trace.SpanKindClient {
+		t.Errorf("query span kind = %v, want client", query.SpanKind)
+	}
+
+	tmdbSpan := findSpan(t, spans, "TMDB /discover/movie")
+	assertChild(t, server, tmdbSpan)
+
+	var upstream []tracetest.SpanStub
+	for _, span := range spans {
+		if span.SpanKind == trace.SpanKindClient && span.Parent.SpanID() == tmdbSpan.SpanContext.SpanID() {
+			upstream = append(upstream, span)
+		}
+	}
+	if len(upstream) != 1 {
+		t.Fatalf("got %d TMDB client spans under %q, want 1", len(upstream), tmdbSpan.Name)
+	}
+}
+
+func TestRedisSpans(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR not set, skipping Redis test")
+	}
+	exporter := installExporter(t)
+
+	client, err := database.NewRedisClient(database.RedisConfig{Addr: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	exporter.Reset()
+	serve(t, "GET /api/movies", "/api/movies", func(w http.ResponseWriter, r *http.Request) {
+		if err := client.Get(r.Context(), "tracing-test:"+uuid.NewString()).Err(); !errors.Is(err, redis.Nil) {
+			t.Errorf("Get: %v, want redis.Nil", err)
+		}
+	})
+
+	spans := exporter.GetSpans()
+	server := findSpan(t, spans, "GET /api/movies")
+	get := findSpan(t, spans, "get")
+	assertChild(t, server, get)
+	if get.Status.Code != codes.Unset {
+		t.Errorf("span status = %v, want unset for a cache miss", get.Status)
+	}
+}
+
+// startFakePostgres serves just enough of the Postgres protocol for pgx to connect
+// and run simple queries, each of which completes without rows. It returns the URL to
+// connect to.
+func startFakePostgres(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakePostgres(conn)
+		}
+	}()
+	return "postgres://test@" + ln.Addr().String() + "/test?sslmode=disable"
+}
+
+func serveFakePostgres(conn net.Conn) {
+	defer conn.Close()
+
+	backend := pgproto3.NewBackend(conn, conn)
+	if _, err := backend.ReceiveStartupMessage(); err != nil {
+		return
+	}
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if err := backend.Flush(); err != nil {
+		return
+	}
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+		switch msg.(type) {
+		case *pgproto3.Query:
+			backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 0")})
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+			if err := backend.Flush(); err != nil {
+				return
+			}
+		case *pgproto3.Terminate:
+			return
+		}
+	}
+}